@@ -0,0 +1,78 @@
+package concmap
+
+import (
+	"context"
+
+	"github.com/morehao/golib/conc/concsem"
+)
+
+// Map 使用 n 个 worker 并发处理 items，结果按输入顺序返回
+// 任一任务出错（包括 panic）时取消其余任务并返回该错误，n 小于等于 0 时不限制并发数
+func Map[T, R any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	err := run(ctx, items, n, func(ctx context.Context, i int, item T) error {
+		res, err := fn(ctx, item)
+		if err != nil {
+			return err
+		}
+		results[i] = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ForEach 使用 n 个 worker 并发处理 items，错误处理与 Map 一致
+func ForEach[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) error) error {
+	return run(ctx, items, n, func(ctx context.Context, _ int, item T) error {
+		return fn(ctx, item)
+	})
+}
+
+// Filter 使用 n 个 worker 并发判断 items，返回 fn 结果为 true 的元素，保持输入顺序，错误处理与 Map 一致
+func Filter[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) (bool, error)) ([]T, error) {
+	keep := make([]bool, len(items))
+	err := run(ctx, items, n, func(ctx context.Context, i int, item T) error {
+		ok, err := fn(ctx, item)
+		if err != nil {
+			return err
+		}
+		keep[i] = ok
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]T, 0, len(items))
+	for i, item := range items {
+		if keep[i] {
+			results = append(results, item)
+		}
+	}
+	return results, nil
+}
+
+// run 基于 concsem 的快速失败模式执行任务，返回首个错误
+func run[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, i int, item T) error) error {
+	if len(items) == 0 {
+		return ctx.Err()
+	}
+	if n <= 0 || n > len(items) {
+		n = len(items)
+	}
+
+	ctrl := concsem.NewControl(n, concsem.WithContext(ctx), concsem.WithFailFast())
+	for i, item := range items {
+		ctrl.RunCtx(func(ctx context.Context) error {
+			return fn(ctx, i, item)
+		})
+	}
+	if errs := ctrl.Wait(); len(errs) > 0 {
+		return errs[0]
+	}
+	// 父上下文取消时，未执行的任务会被直接丢弃
+	return ctx.Err()
+}
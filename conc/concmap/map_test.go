@@ -0,0 +1,65 @@
+package concmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	ctx := context.Background()
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	res, err := Map(ctx, items, 3, func(ctx context.Context, item int) (string, error) {
+		time.Sleep(time.Millisecond * time.Duration(10-item))
+		return fmt.Sprintf("item-%d", item), nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, res, len(items))
+	for i, item := range items {
+		assert.Equal(t, fmt.Sprintf("item-%d", item), res[i])
+	}
+}
+
+func TestMapError(t *testing.T) {
+	ctx := context.Background()
+	items := []int{1, 2, 3, 4, 5}
+	targetErr := errors.New("item 3 failed")
+	res, err := Map(ctx, items, 2, func(ctx context.Context, item int) (int, error) {
+		if item == 3 {
+			return 0, targetErr
+		}
+		return item * 2, nil
+	})
+	assert.Equal(t, targetErr, err)
+	assert.Nil(t, res)
+
+	_, panicErr := Map(ctx, items, 2, func(ctx context.Context, item int) (int, error) {
+		if item == 2 {
+			panic("boom")
+		}
+		return item, nil
+	})
+	assert.ErrorContains(t, panicErr, "boom")
+}
+
+func TestForEach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ForEach(ctx, []int{1, 2, 3}, 2, func(ctx context.Context, item int) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	res, err := Filter(ctx, []int{1, 2, 3, 4, 5, 6}, 0, func(ctx context.Context, item int) (bool, error) {
+		return item%2 == 0, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 4, 6}, res)
+}
@@ -0,0 +1,151 @@
+package concmap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Pipeline 流式处理流水线，各阶段之间通过有界 channel 连接
+// 任一阶段出错（包括 panic）时取消整条流水线，Wait 返回首个错误
+type Pipeline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex // 用于保护 err
+	err    error      // 首个错误
+}
+
+// NewPipeline 创建一条新的流水线，ctx 取消时所有阶段都会退出
+func NewPipeline(ctx context.Context) *Pipeline {
+	pipeCtx, cancel := context.WithCancel(ctx)
+	return &Pipeline{
+		parent: ctx,
+		ctx:    pipeCtx,
+		cancel: cancel,
+	}
+}
+
+// Context 返回流水线的上下文，流水线出错或结束时会被取消
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Wait 等待所有阶段退出并返回首个错误
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	defer p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	// 父上下文取消时，流水线中的数据可能没有处理完
+	return p.parent.Err()
+}
+
+// fail 记录首个错误并取消流水线
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
+
+// Source 将 items 依次写入流水线，返回作为首个阶段输入的 channel
+func Source[T any](p *Pipeline, items []T) <-chan T {
+	out := make(chan T)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+
+		for _, item := range items {
+			select {
+			case out <- item:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// AddStage 添加一个处理阶段，使用 workers 个协程消费 in，结果写入容量为 buffer 的 channel
+// 多个 worker 并发处理时输出顺序与输入顺序不保证一致
+func AddStage[In, Out any](p *Pipeline, in <-chan In, workers, buffer int, fn func(ctx context.Context, item In) (Out, error)) <-chan Out {
+	if workers <= 0 {
+		workers = 1
+	}
+	out := make(chan Out, buffer)
+
+	var stageWg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		stageWg.Add(1)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer stageWg.Done()
+
+			for {
+				var item In
+				var ok bool
+				select {
+				case item, ok = <-in:
+					if !ok {
+						return
+					}
+				case <-p.ctx.Done():
+					return
+				}
+
+				res, err := executeStage(p.ctx, fn, item)
+				if err != nil {
+					p.fail(err)
+					return
+				}
+
+				select {
+				case out <- res:
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// 当前阶段所有 worker 退出后关闭输出 channel，通知下游阶段
+	go func() {
+		stageWg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Collect 读取最后一个阶段的全部输出，并等待流水线结束
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	results := make([]T, 0)
+	for item := range in {
+		results = append(results, item)
+	}
+	if err := p.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// executeStage 执行阶段处理函数，并将panic转换为错误
+func executeStage[In, Out any](ctx context.Context, fn func(ctx context.Context, item In) (Out, error), item In) (res Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panic: %v", r)
+		}
+	}()
+
+	return fn(ctx, item)
+}
@@ -0,0 +1,45 @@
+package concmap
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(context.Background())
+	ids := Source(p, []int{1, 2, 3, 4, 5})
+	doubled := AddStage(p, ids, 3, 2, func(ctx context.Context, id int) (int, error) {
+		return id * 2, nil
+	})
+	strs := AddStage(p, doubled, 2, 2, func(ctx context.Context, n int) (string, error) {
+		return strconv.Itoa(n), nil
+	})
+	res, err := Collect(p, strs)
+	assert.Nil(t, err)
+	sort.Strings(res)
+	assert.Equal(t, []string{"10", "2", "4", "6", "8"}, res)
+}
+
+func TestPipelineError(t *testing.T) {
+	p := NewPipeline(context.Background())
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	targetErr := errors.New("stage failed")
+	ids := Source(p, items)
+	stage := AddStage(p, ids, 4, 0, func(ctx context.Context, id int) (int, error) {
+		if id == 10 {
+			return 0, targetErr
+		}
+		return id, nil
+	})
+	res, err := Collect(p, stage)
+	assert.Equal(t, targetErr, err)
+	assert.Nil(t, res)
+}
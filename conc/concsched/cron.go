@@ -0,0 +1,240 @@
+package concsched
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 描述任务的触发时间
+type Schedule interface {
+	// Next 返回晚于 t 的下一次触发时间，返回零值表示不再触发
+	Next(t time.Time) time.Time
+}
+
+// everySchedule 固定间隔触发
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Every 创建固定间隔触发的 Schedule，触发时间按 interval 对齐到时钟整点，
+// 与启动时间无关，多个实例的同一周期得到相同的触发时间
+func Every(interval time.Duration) Schedule {
+	return everySchedule{interval: interval}
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// cronSchedule 标准5段式 cron 表达式：分 时 日 月 周
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	loc                           *time.Location
+}
+
+type bounds struct {
+	min, max uint
+}
+
+var (
+	minuteBounds = bounds{0, 59}
+	hourBounds   = bounds{0, 23}
+	domBounds    = bounds{1, 31}
+	monthBounds  = bounds{1, 12}
+	dowBounds    = bounds{0, 7}
+)
+
+// starBit 标记字段为 *，用于日和周同时设置时的匹配规则
+const starBit = 1 << 63
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron 解析5段式 cron 表达式，支持 *、?、范围、步长、列表以及 @daily 等预定义表达式
+// 触发时间按 loc 计算，loc 为空时使用本地时区
+func ParseCron(spec string, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := descriptors[spec]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized descriptor: %s", spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, found %d: %s", len(fields), spec)
+	}
+
+	var err error
+	s := &cronSchedule{loc: loc}
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	// 周日既可以写成0也可以写成7
+	if s.dow&(1<<7) > 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseField 将单个字段解析为位图
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		exprBits, err := parseExpr(expr, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= exprBits
+	}
+	return bits, nil
+}
+
+// parseExpr 解析 *、a、a-b、*/n、a-b/n、a/n 形式的表达式
+func parseExpr(expr string, b bounds) (uint64, error) {
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("too many slashes: %s", expr)
+	}
+
+	var start, end uint
+	var extra uint64
+	switch rangeExpr := rangeAndStep[0]; rangeExpr {
+	case "*", "?":
+		start, end = b.min, b.max
+		extra = starBit
+	default:
+		lowAndHigh := strings.Split(rangeExpr, "-")
+		if len(lowAndHigh) > 2 {
+			return 0, fmt.Errorf("too many hyphens: %s", expr)
+		}
+		var err error
+		if start, err = parseUint(lowAndHigh[0]); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseUint(lowAndHigh[1]); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	step := uint(1)
+	if len(rangeAndStep) == 2 {
+		var err error
+		if step, err = parseUint(rangeAndStep[1]); err != nil {
+			return 0, err
+		}
+		if step == 0 {
+			return 0, fmt.Errorf("step of range should be a positive number: %s", expr)
+		}
+		// a/n 表示从 a 开始到最大值
+		if !strings.Contains(rangeAndStep[0], "-") && extra == 0 {
+			end = b.max
+		}
+		// 带步长时不再视为 *
+		extra = 0
+	}
+
+	if start < b.min || end > b.max {
+		return 0, fmt.Errorf("value out of range [%d, %d]: %s", b.min, b.max, expr)
+	}
+	if start > end {
+		return 0, fmt.Errorf("beginning of range after end: %s", expr)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits | extra, nil
+}
+
+func parseUint(s string) (uint, error) {
+	n, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse number %q: %w", s, err)
+	}
+	return uint(n), nil
+}
+
+// Next 依次从月、日、时、分匹配，找到晚于 t 的最近触发时间，五年内找不到时返回零值
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	t = t.In(s.loc)
+	// 从下一分钟开始匹配
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, s.loc).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLoc)
+}
+
+// dayMatches 日和周都不是 * 时满足其一即可，否则需要同时满足
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+	if s.dom&starBit > 0 || s.dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
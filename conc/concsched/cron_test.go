@@ -0,0 +1,55 @@
+package concsched
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2025, 3, 10, 10, 15, 30, 0, time.UTC) // 周一
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 10, 10, 16, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, 3, 10, 10, 20, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2025, 3, 11, 2, 30, 0, 0, time.UTC)},
+		{"0 9-18/3 * * 1-5", time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2025, 3, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{"5,10 10 * * ?", time.Date(2025, 3, 11, 10, 5, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCron(c.spec, time.UTC)
+		assert.Nil(t, err, c.spec)
+		assert.Equal(t, c.next, schedule.Next(base), c.spec)
+	}
+
+	never, err := ParseCron("0 0 30 2 *", time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, never.Next(base).IsZero())
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{"* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every"}
+	for _, spec := range specs {
+		_, err := ParseCron(spec, time.UTC)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	schedule := Every(time.Minute * 5)
+	base := time.Date(2025, 3, 10, 10, 17, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 3, 10, 10, 20, 0, 0, time.UTC), schedule.Next(base))
+	// 不同时间启动的实例得到相同的触发时间
+	assert.Equal(t, schedule.Next(base), schedule.Next(base.Add(time.Minute*2)))
+	assert.Equal(t, time.Date(2025, 3, 10, 10, 25, 0, 0, time.UTC), schedule.Next(schedule.Next(base)))
+}
@@ -0,0 +1,74 @@
+package concsched
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/morehao/golib/distlock"
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// OverlapPolicy 上一次执行未结束时到达新周期的处理策略
+type OverlapPolicy int
+
+const (
+	OverlapSkip  OverlapPolicy = iota // 跳过本次执行
+	OverlapAllow                      // 允许并发执行
+	OverlapWait                       // 等待上一次执行结束后再执行
+)
+
+// MissedPolicy 错过触发时间（如工作池繁忙、进程挂起）时的处理策略
+type MissedPolicy int
+
+const (
+	MissedSkip    MissedPolicy = iota // 跳过错过的周期，从当前时间重新计算
+	MissedRunOnce                     // 立即补执行一次，多个错过的周期合并为一次
+)
+
+// LockFactory 根据任务名和触发时间创建分布式锁
+type LockFactory func(name string, tick time.Time) *distlock.DistLock
+
+// Option 是一个函数类型，用于设置 Scheduler 的选项
+type Option func(s *Scheduler)
+
+// WithLocation 设置 cron 表达式使用的时区
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) {
+		s.loc = loc
+	}
+}
+
+// JobOption 是一个函数类型，用于设置单个任务的选项
+type JobOption func(e *entry)
+
+// WithOverlapPolicy 设置任务的重叠执行策略，默认为 OverlapSkip
+func WithOverlapPolicy(policy OverlapPolicy) JobOption {
+	return func(e *entry) {
+		e.overlap = policy
+	}
+}
+
+// WithMissedPolicy 设置任务错过触发时间时的处理策略，默认为 MissedSkip
+func WithMissedPolicy(policy MissedPolicy) JobOption {
+	return func(e *entry) {
+		e.missed = policy
+	}
+}
+
+// WithDistLock 使用分布式锁保证每个周期只有一个实例执行任务
+// 锁在任务结束后不会主动释放，依靠 TTL 过期，防止其他实例在同一周期内重复执行，
+// 因此 newLock 应按触发时间区分锁的 key，并且不开启自动续期
+func WithDistLock(newLock LockFactory) JobOption {
+	return func(e *entry) {
+		e.newLock = newLock
+	}
+}
+
+// NewRedisLockFactory 创建基于 Redis 的 LockFactory，锁的 key 为 prefix:任务名:触发时间戳
+// ttl 需要大于各实例之间的时钟偏差，并小于任务的触发间隔
+func NewRedisLockFactory(client goredislib.UniversalClient, prefix string, ttl time.Duration) LockFactory {
+	locker := distlock.NewRedisLocker(client, distlock.Config{TTL: ttl}, distlock.WithKeyPrefix(prefix))
+	return func(name string, tick time.Time) *distlock.DistLock {
+		return locker.NewLock(fmt.Sprintf("%s:%d", name, tick.Unix()))
+	}
+}
@@ -0,0 +1,208 @@
+package concsched
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/morehao/golib/conc/concpool"
+	"github.com/morehao/golib/distlock"
+	"github.com/morehao/golib/glog"
+)
+
+// Job 表示一个周期执行的任务
+type Job func(ctx context.Context) error
+
+// Scheduler 周期任务调度器，任务到期后提交到工作池执行
+type Scheduler struct {
+	pool    concpool.Pool
+	loc     *time.Location
+	mu      sync.Mutex // 用于保护 entries 和 started
+	entries map[string]*entry
+	started bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// entry 表示一个已注册的任务
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+	overlap  OverlapPolicy
+	missed   MissedPolicy
+	newLock  LockFactory
+	running  chan struct{} // 重叠策略为 OverlapSkip、OverlapWait 时使用，容量为1
+}
+
+// New 创建一个新的调度器，任务通过 pool 执行，pool 的生命周期由调用方管理
+func New(pool concpool.Pool, options ...Option) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		pool:    pool,
+		loc:     time.Local,
+		entries: make(map[string]*entry),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// AddCron 注册按 cron 表达式触发的任务，name 在调度器内唯一
+func (s *Scheduler) AddCron(name, spec string, job Job, options ...JobOption) error {
+	schedule, err := ParseCron(spec, s.loc)
+	if err != nil {
+		return err
+	}
+	return s.AddSchedule(name, schedule, job, options...)
+}
+
+// AddInterval 注册按固定间隔触发的任务，name 在调度器内唯一
+func (s *Scheduler) AddInterval(name string, interval time.Duration, job Job, options ...JobOption) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	return s.AddSchedule(name, Every(interval), job, options...)
+}
+
+// AddSchedule 注册按自定义 Schedule 触发的任务，name 在调度器内唯一
+func (s *Scheduler) AddSchedule(name string, schedule Schedule, job Job, options ...JobOption) error {
+	e := &entry{
+		name:     name,
+		schedule: schedule,
+		job:      job,
+	}
+	for _, opt := range options {
+		opt(e)
+	}
+	if e.overlap != OverlapAllow {
+		e.running = make(chan struct{}, 1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("job %s already exists", name)
+	}
+	s.entries[name] = e
+	if s.started {
+		s.wg.Add(1)
+		go s.runEntry(e)
+	}
+	return nil
+}
+
+// Start 启动调度器，重复调用无效
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.runEntry(e)
+	}
+}
+
+// Stop 停止调度新的周期并等待调度协程退出，已提交到工作池的任务不受影响
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// runEntry 单个任务的调度循环
+func (s *Scheduler) runEntry(e *entry) {
+	defer s.wg.Done()
+
+	next := e.schedule.Next(time.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		tick := next
+		now := time.Now()
+		// 当前时间已经超过下一个周期，说明错过了触发时间
+		following := e.schedule.Next(tick)
+		missed := !following.IsZero() && !now.Before(following)
+		if missed {
+			glog.Warnf(s.ctx, "[concsched] job %s missed tick %s", e.name, tick.Format(time.DateTime))
+		}
+		if !missed || e.missed == MissedRunOnce {
+			s.fire(e, tick)
+		}
+
+		next = following
+		if missed {
+			next = e.schedule.Next(now)
+		}
+	}
+}
+
+// fire 按重叠策略将任务提交到工作池
+func (s *Scheduler) fire(e *entry, tick time.Time) {
+	if e.running != nil {
+		if e.overlap == OverlapWait {
+			select {
+			case e.running <- struct{}{}:
+			case <-s.ctx.Done():
+				return
+			}
+		} else {
+			select {
+			case e.running <- struct{}{}:
+			default:
+				glog.Warnf(s.ctx, "[concsched] job %s is still running, skip tick %s", e.name, tick.Format(time.DateTime))
+				return
+			}
+		}
+	}
+	release := func() {
+		if e.running != nil {
+			<-e.running
+		}
+	}
+
+	submitted := s.pool.Submit(func(ctx context.Context) error {
+		defer release()
+		return s.execute(ctx, e, tick)
+	})
+	if !submitted {
+		release()
+		glog.Warnf(s.ctx, "[concsched] job %s rejected by pool, skip tick %s", e.name, tick.Format(time.DateTime))
+	}
+}
+
+// execute 获取分布式锁后执行任务
+func (s *Scheduler) execute(ctx context.Context, e *entry, tick time.Time) error {
+	if e.newLock != nil {
+		// 只尝试一次，未获取到锁说明其他实例已经执行了本周期的任务
+		dl := e.newLock(e.name, tick)
+		if _, err := dl.TryLock(ctx); err != nil {
+			if errors.Is(err, distlock.ErrLockHeld) {
+				glog.Debugf(ctx, "[concsched] job %s tick %s is held by another instance", e.name, tick.Format(time.DateTime))
+				return nil
+			}
+			glog.Errorf(ctx, "[concsched] job %s tick %s acquire lock failed: %v", e.name, tick.Format(time.DateTime), err)
+			return err
+		}
+	}
+
+	if err := e.job(ctx); err != nil {
+		glog.Errorf(ctx, "[concsched] job %s tick %s failed: %v", e.name, tick.Format(time.DateTime), err)
+		return err
+	}
+	return nil
+}
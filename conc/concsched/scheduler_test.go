@@ -0,0 +1,172 @@
+package concsched
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/morehao/golib/conc/concpool"
+	"github.com/morehao/golib/distlock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_AddInterval(t *testing.T) {
+	pool := concpool.New(2, 10)
+	defer pool.Shutdown()
+
+	s := New(pool)
+	var count int32
+	err := s.AddInterval("counter", time.Millisecond*50, func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, s.AddInterval("counter", time.Second, func(ctx context.Context) error { return nil }))
+
+	s.Start()
+	time.Sleep(time.Millisecond * 280)
+	s.Stop()
+	got := atomic.LoadInt32(&count)
+	assert.GreaterOrEqual(t, got, int32(4))
+	assert.LessOrEqual(t, got, int32(6))
+}
+
+func TestScheduler_OverlapSkip(t *testing.T) {
+	pool := concpool.New(4, 10)
+	defer pool.Shutdown()
+
+	s := New(pool)
+	var running, maxRunning int32
+	err := s.AddInterval("slow", time.Millisecond*20, func(ctx context.Context) error {
+		cur := atomic.AddInt32(&running, 1)
+		if cur > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, cur)
+		}
+		time.Sleep(time.Millisecond * 70)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+	assert.Nil(t, err)
+	s.Start()
+	time.Sleep(time.Millisecond * 300)
+	s.Stop()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning))
+}
+
+// memoryLockStore 模拟多个实例共享的锁存储
+type memoryLockStore struct {
+	mu   *sync.Mutex
+	held map[string]bool
+	key  string
+}
+
+func (m *memoryLockStore) Lock(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.held[m.key] {
+		return false, nil
+	}
+	m.held[m.key] = true
+	return true, nil
+}
+
+func (m *memoryLockStore) TryLock(ctx context.Context) (bool, error) {
+	return m.Lock(ctx)
+}
+
+func (m *memoryLockStore) Unlock(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.held, m.key)
+	return true, nil
+}
+
+func (m *memoryLockStore) Renewal(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func TestScheduler_WithDistLock(t *testing.T) {
+	var mu sync.Mutex
+	held := make(map[string]bool)
+	newLock := func(name string, tick time.Time) *distlock.DistLock {
+		config := distlock.Config{Key: name + tick.String(), TTL: time.Second}
+		store := &memoryLockStore{mu: &mu, held: held, key: config.Key}
+		return distlock.NewDistLock(store, &config)
+	}
+
+	var count int32
+	job := func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}
+	// 两个实例使用相同的时间表，每个周期只有一个实例执行
+	schedule := fixedSchedule{start: time.Now().Add(time.Millisecond * 50), interval: time.Millisecond * 50, times: 3}
+	var schedulers []*Scheduler
+	for i := 0; i < 2; i++ {
+		pool := concpool.New(2, 10)
+		defer pool.Shutdown()
+		s := New(pool)
+		assert.Nil(t, s.AddSchedule("report", schedule, job, WithDistLock(newLock)))
+		s.Start()
+		schedulers = append(schedulers, s)
+	}
+	time.Sleep(time.Millisecond * 300)
+	for _, s := range schedulers {
+		s.Stop()
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestScheduler_EveryWithDistLock(t *testing.T) {
+	var mu sync.Mutex
+	held := make(map[string]bool)
+	newLock := func(name string, tick time.Time) *distlock.DistLock {
+		config := distlock.Config{Key: name + tick.String(), TTL: time.Second}
+		store := &memoryLockStore{mu: &mu, held: held, key: config.Key}
+		return distlock.NewDistLock(store, &config)
+	}
+
+	var count int32
+	job := func(ctx context.Context) error {
+		atomic.AddInt32(&count, 1)
+		return nil
+	}
+	// 两个实例在不同时间启动，触发时间对齐到时钟，每个周期只有一个实例执行
+	interval := time.Millisecond * 50
+	var schedulers []*Scheduler
+	for i := 0; i < 2; i++ {
+		pool := concpool.New(2, 10)
+		defer pool.Shutdown()
+		s := New(pool)
+		assert.Nil(t, s.AddInterval("report", interval, job, WithDistLock(newLock)))
+		s.Start()
+		schedulers = append(schedulers, s)
+		time.Sleep(time.Millisecond * 17)
+	}
+	time.Sleep(time.Millisecond * 300)
+	for _, s := range schedulers {
+		s.Stop()
+	}
+	got := atomic.LoadInt32(&count)
+	assert.GreaterOrEqual(t, got, int32(5))
+	assert.LessOrEqual(t, got, int32(7))
+}
+
+// fixedSchedule 从 start 开始按 interval 触发 times 次，各实例的触发时间完全一致
+type fixedSchedule struct {
+	start    time.Time
+	interval time.Duration
+	times    int
+}
+
+func (s fixedSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < s.times; i++ {
+		tick := s.start.Add(s.interval * time.Duration(i))
+		if tick.After(t) {
+			return tick
+		}
+	}
+	return time.Time{}
+}
@@ -2,36 +2,77 @@ package concsem
 
 import (
 	"context"
+	"fmt"
 	"sync"
 )
 
 // Control 控制并发执行任务的控制器
 type Control struct {
 	wg        sync.WaitGroup
-	workerNum int           // 并发数
-	sem       chan struct{} // 用于控制并发数的信号量
+	workerNum int       // 并发数
+	sem       *weighted // 用于控制并发数的信号量
 	ctx       context.Context
 	cancel    context.CancelFunc
-	errors    []error    // 存储任务执行过程中的错误
-	closed    bool       // 标志控制器是否已关闭
-	mu        sync.Mutex // 用于保护 closed 状态和errors
-	once      sync.Once  // 确保 Close 只执行一次
+	failFast  bool               // 是否在首个错误出现时取消其余任务
+	keyLimit  int                // 每个 key 允许占用的最大并发数，0 表示不限制
+	keySems   map[string]*keySem // 每个 key 对应的信号量
+	keyMu     sync.Mutex         // 用于保护 keySems
+	errors    []error            // 存储任务执行过程中的错误
+	closed    bool               // 标志控制器是否已关闭
+	mu        sync.Mutex         // 用于保护 closed 状态和errors
+	once      sync.Once          // 确保 Close 只执行一次
+}
+
+// keySem 是单个 key 的信号量，refs 为持有或等待该信号量的任务数，归零时回收
+type keySem struct {
+	sem  *weighted
+	refs int
 }
 
 // NewControl 创建一个新的并发控制器，workerNum 指定并发执行的工作数
-func NewControl(workerNum int) *Control {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Control{
+func NewControl(workerNum int, options ...Option) *Control {
+	ctrl := &Control{
 		workerNum: workerNum,
-		sem:       make(chan struct{}, workerNum), // 初始化信号量
-		ctx:       ctx,
-		cancel:    cancel,
+		sem:       newWeighted(int64(workerNum)), // 初始化信号量
+		ctx:       context.Background(),
+		keySems:   make(map[string]*keySem),
 		errors:    make([]error, 0),
 	}
+	for _, opt := range options {
+		opt(ctrl)
+	}
+	ctrl.ctx, ctrl.cancel = context.WithCancel(ctrl.ctx)
+	return ctrl
 }
 
 // Run 执行传入的方法，控制并发执行的数量
 func (ctrl *Control) Run(task func() error) {
+	ctrl.RunCtx(func(ctx context.Context) error {
+		return task()
+	})
+}
+
+// RunCtx 执行传入的方法，控制并发执行的数量，任务可以通过 ctx 感知控制器的取消
+func (ctrl *Control) RunCtx(task func(ctx context.Context) error) {
+	ctrl.run("", 1, task)
+}
+
+// RunWeighted 执行传入的方法，任务占用 weight 个并发数，适用于资源消耗不同的任务
+func (ctrl *Control) RunWeighted(weight int, task func(ctx context.Context) error) {
+	ctrl.run("", weight, task)
+}
+
+// RunKey 执行传入的方法，任务同时受全局并发数和 key 的并发数限制，key 的限制通过 WithKeyLimit 设置
+func (ctrl *Control) RunKey(key string, task func(ctx context.Context) error) {
+	ctrl.run(key, 1, task)
+}
+
+// RunKeyWeighted 执行传入的方法，任务在全局和 key 的限制中都占用 weight 个并发数
+func (ctrl *Control) RunKeyWeighted(key string, weight int, task func(ctx context.Context) error) {
+	ctrl.run(key, weight, task)
+}
+
+func (ctrl *Control) run(key string, weight int, task func(ctx context.Context) error) {
 	ctrl.mu.Lock()
 	if ctrl.closed {
 		ctrl.mu.Unlock()
@@ -44,32 +85,129 @@ func (ctrl *Control) Run(task func() error) {
 		return
 	}
 
-	select {
-	case ctrl.sem <- struct{}{}: // 获取信号量
-	case <-ctrl.ctx.Done(): // 检查是否已经调用了cancel
+	if weight < 1 {
+		weight = 1
+	}
+	if weight > ctrl.workerNum {
+		ctrl.addError(fmt.Errorf("task weight %d exceeds worker num %d", weight, ctrl.workerNum))
+		return
+	}
+	useKey := key != "" && ctrl.keyLimit > 0
+	if useKey && weight > ctrl.keyLimit {
+		ctrl.addError(fmt.Errorf("task weight %d exceeds key limit %d", weight, ctrl.keyLimit))
+		return
+	}
+
+	if useKey {
+		// key 的信号量在协程中获取，某个 key 占满时不会阻塞其他 key 提交任务
+		ctrl.wg.Add(1)
+		go func() {
+			defer ctrl.wg.Done()
+			// 先获取 key 的信号量再获取全局信号量，避免等待 key 的任务占用全局并发数
+			if err := ctrl.acquireKey(key, int64(weight)); err != nil {
+				return
+			}
+			defer ctrl.releaseKey(key, int64(weight))
+			if err := ctrl.sem.acquire(ctrl.ctx, int64(weight)); err != nil {
+				return
+			}
+			defer ctrl.sem.release(int64(weight))
+			ctrl.exec(task)
+		}()
+		return
+	}
+
+	if err := ctrl.sem.acquire(ctrl.ctx, int64(weight)); err != nil {
 		return
 	}
 
 	ctrl.wg.Add(1)
 	go func() {
-		defer ctrl.wg.Done()          // 完成后减少等待计数
-		defer func() { <-ctrl.sem }() // 释放信号量
+		defer ctrl.wg.Done()                  // 完成后减少等待计数
+		defer ctrl.sem.release(int64(weight)) // 释放信号量
+		ctrl.exec(task)
+	}()
+}
 
-		// 检查是否已经调用了cancel，如果是，则提前退出
-		if err := ctrl.ctx.Err(); err != nil {
-			return
-		}
+// exec 在持有信号量的情况下执行任务并记录错误
+func (ctrl *Control) exec(task func(ctx context.Context) error) {
+	// 检查是否已经调用了cancel，如果是，则提前退出
+	if err := ctrl.ctx.Err(); err != nil {
+		return
+	}
+
+	// 执行任务并处理错误
+	if err := ctrl.execute(task); err != nil {
+		ctrl.addError(err)
+	}
+}
+
+// acquireKey 获取 key 对应的信号量
+func (ctrl *Control) acquireKey(key string, n int64) error {
+	ctrl.keyMu.Lock()
+	ks, ok := ctrl.keySems[key]
+	if !ok {
+		ks = &keySem{sem: newWeighted(int64(ctrl.keyLimit))}
+		ctrl.keySems[key] = ks
+	}
+	ks.refs++
+	ctrl.keyMu.Unlock()
+
+	if err := ks.sem.acquire(ctrl.ctx, n); err != nil {
+		ctrl.unrefKey(key, ks)
+		return err
+	}
+	return nil
+}
 
-		// 执行任务并处理错误
-		if err := task(); err != nil {
-			ctrl.mu.Lock()
-			ctrl.errors = append(ctrl.errors, err)
-			ctrl.mu.Unlock()
+// releaseKey 释放 key 对应的信号量
+func (ctrl *Control) releaseKey(key string, n int64) {
+	ctrl.keyMu.Lock()
+	ks := ctrl.keySems[key]
+	ctrl.keyMu.Unlock()
+
+	ks.sem.release(n)
+	ctrl.unrefKey(key, ks)
+}
+
+// unrefKey 减少 key 的引用计数，没有任务使用时回收信号量
+func (ctrl *Control) unrefKey(key string, ks *keySem) {
+	ctrl.keyMu.Lock()
+	defer ctrl.keyMu.Unlock()
+
+	ks.refs--
+	if ks.refs == 0 {
+		delete(ctrl.keySems, key)
+	}
+}
+
+// execute 执行任务，并将任务中的panic转换为错误
+func (ctrl *Control) execute(task func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panic: %v", r)
 		}
 	}()
+
+	return task(ctrl.ctx)
 }
 
-// Wait 等待所有任务完成并返回错误列表
+// addError 记录任务错误，快速失败模式下只保留首个错误并取消其余任务
+func (ctrl *Control) addError(err error) {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+
+	if !ctrl.failFast {
+		ctrl.errors = append(ctrl.errors, err)
+		return
+	}
+	if len(ctrl.errors) == 0 {
+		ctrl.errors = append(ctrl.errors, err)
+		ctrl.cancel()
+	}
+}
+
+// Wait 等待所有任务完成并返回错误列表，快速失败模式下只包含首个错误
 func (ctrl *Control) Wait() []error {
 	ctrl.wg.Wait()
 
@@ -82,12 +220,13 @@ func (ctrl *Control) Wait() []error {
 	return errorsCopy
 }
 
-// Close 关闭控制器，取消所有任务并关闭错误 channel
+// Close 关闭控制器，取消所有任务并等待正在执行的任务退出
 func (ctrl *Control) Close() {
 	ctrl.once.Do(func() {
 		ctrl.mu.Lock()
-		defer ctrl.mu.Unlock()
 		ctrl.closed = true
+		ctrl.mu.Unlock()
+
 		ctrl.cancel() // 取消所有剩余的任务
 		ctrl.Wait()   // 等待所有任务完成
 	})
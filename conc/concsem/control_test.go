@@ -2,9 +2,13 @@ package concsem
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestControl_Run(t *testing.T) {
@@ -41,3 +45,118 @@ func TestControl_Run(t *testing.T) {
 		fmt.Println("Error:", err)
 	}
 }
+
+func TestControl_RunCtxFailFast(t *testing.T) {
+	ctrl := NewControl(3, WithFailFast())
+	firstErr := errors.New("task failed")
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		n := i
+		ctrl.RunCtx(func(ctx context.Context) error {
+			if n == 0 {
+				return firstErr
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second * 3):
+				return nil
+			}
+		})
+	}
+	errs := ctrl.Wait()
+	assert.Equal(t, []error{firstErr}, errs)
+	// 其余任务应被取消，而不是等待超时
+	assert.Less(t, time.Since(start), time.Second*3)
+
+	// 快速失败后提交的任务不会再执行
+	ctrl.RunCtx(func(ctx context.Context) error {
+		t.Fatal("任务不应被执行")
+		return nil
+	})
+	assert.Len(t, ctrl.Wait(), 1)
+}
+
+func TestControl_RunPanic(t *testing.T) {
+	ctrl := NewControl(2)
+	ctrl.Run(func() error {
+		panic("boom")
+	})
+	ctrl.Run(func() error {
+		return nil
+	})
+	errs := ctrl.Wait()
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "boom")
+}
+
+func TestControl_Close(t *testing.T) {
+	ctrl := NewControl(1)
+	ctrl.RunCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctrl.Close()
+	assert.Panics(t, func() {
+		ctrl.Run(func() error { return nil })
+	})
+}
+
+func TestControl_RunWeighted(t *testing.T) {
+	ctrl := NewControl(4)
+	var running, maxRunning int32
+	task := func(weight int32) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			cur := atomic.AddInt32(&running, weight)
+			for {
+				old := atomic.LoadInt32(&maxRunning)
+				if cur <= old || atomic.CompareAndSwapInt32(&maxRunning, old, cur) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 100)
+			atomic.AddInt32(&running, -weight)
+			return nil
+		}
+	}
+	ctrl.RunWeighted(4, task(4))
+	for i := 0; i < 4; i++ {
+		ctrl.RunWeighted(1, task(1))
+	}
+	ctrl.RunWeighted(3, task(3))
+	// 权重超过并发数的任务直接返回错误
+	ctrl.RunWeighted(5, task(5))
+	errs := ctrl.Wait()
+	assert.Len(t, errs, 1)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(4))
+}
+
+func TestControl_RunKey(t *testing.T) {
+	ctrl := NewControl(4, WithKeyLimit(1))
+	var tenantRunning, tenantMax int32
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		ctrl.RunKey("tenantA", func(ctx context.Context) error {
+			cur := atomic.AddInt32(&tenantRunning, 1)
+			if cur > atomic.LoadInt32(&tenantMax) {
+				atomic.StoreInt32(&tenantMax, cur)
+			}
+			time.Sleep(time.Millisecond * 100)
+			atomic.AddInt32(&tenantRunning, -1)
+			return nil
+		})
+	}
+	// tenantA 占满自己的配额时，提交 tenantB 的任务不应被阻塞
+	assert.Less(t, time.Since(start), time.Millisecond*50)
+	var tenantBDone time.Duration
+	ctrl.RunKey("tenantB", func(ctx context.Context) error {
+		tenantBDone = time.Since(start)
+		return nil
+	})
+	assert.Empty(t, ctrl.Wait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tenantMax))
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*300)
+	// tenantB 应在 tenantA 的第一个任务结束前执行完成
+	assert.Less(t, tenantBDone, time.Millisecond*100)
+	assert.Empty(t, ctrl.keySems)
+}
@@ -0,0 +1,29 @@
+package concsem
+
+import (
+	"context"
+)
+
+// Option 是一个函数类型，用于设置 Control 的选项
+type Option func(ctrl *Control)
+
+// WithContext 设置 Control 的父上下文，父上下文取消时所有未执行的任务都会被放弃
+func WithContext(ctx context.Context) Option {
+	return func(ctrl *Control) {
+		ctrl.ctx = ctx
+	}
+}
+
+// WithFailFast 开启快速失败模式，首个任务出错时取消共享上下文，Wait 只返回该错误
+func WithFailFast() Option {
+	return func(ctrl *Control) {
+		ctrl.failFast = true
+	}
+}
+
+// WithKeyLimit 设置每个 key 允许占用的最大并发数，配合 RunKey 使用，防止单个 key 占满全局并发数
+func WithKeyLimit(limit int) Option {
+	return func(ctrl *Control) {
+		ctrl.keyLimit = limit
+	}
+}
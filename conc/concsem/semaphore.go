@@ -0,0 +1,89 @@
+package concsem
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// weighted 是支持按权重获取的信号量，等待者按先进先出的顺序获得资源，避免大权重任务被饿死
+type weighted struct {
+	size    int64
+	cur     int64
+	mu      sync.Mutex
+	waiters list.List
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // 获取成功时关闭
+}
+
+func newWeighted(size int64) *weighted {
+	return &weighted{size: size}
+}
+
+// acquire 获取权重为 n 的资源，阻塞直到获取成功或 ctx 被取消
+func (s *weighted) acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// 取消后恰好获取成功，归还资源
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// 队首等待者离开后，后面的等待者可能已经可以获取资源
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release 释放权重为 n 的资源
+func (s *weighted) release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("concsem: released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// notifyWaiters 按顺序唤醒可以获取资源的等待者，调用方需持有锁
+func (s *weighted) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,67 @@
+package concshutdown
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/morehao/golib/conc/concpool"
+	"github.com/morehao/golib/conc/concqueue"
+	"github.com/morehao/golib/conc/concsched"
+	"github.com/morehao/golib/conc/concsem"
+	"github.com/morehao/golib/glog"
+)
+
+// PoolHook 等待工作池中的任务执行完成后关闭
+func PoolHook(pool concpool.Pool) Hook {
+	return func(ctx context.Context) error {
+		pool.Shutdown()
+		return nil
+	}
+}
+
+// QueueHook 等待队列中的任务执行完成后关闭
+func QueueHook(queue concqueue.Queue) Hook {
+	return func(ctx context.Context) error {
+		queue.StopAndWait()
+		return nil
+	}
+}
+
+// ControlHook 取消并发控制器中未执行的任务并等待正在执行的任务退出
+func ControlHook(ctrl *concsem.Control) Hook {
+	return func(ctx context.Context) error {
+		ctrl.Close()
+		return nil
+	}
+}
+
+// SchedulerHook 停止调度新的周期任务
+func SchedulerHook(scheduler *concsched.Scheduler) Hook {
+	return func(ctx context.Context) error {
+		scheduler.Stop()
+		return nil
+	}
+}
+
+// ServerHook 优雅关闭 HTTP 服务
+func ServerHook(server *http.Server) Hook {
+	return func(ctx context.Context) error {
+		return server.Shutdown(ctx)
+	}
+}
+
+// CloserHook 关闭实现了 io.Closer 的组件，如 *sql.DB、*redis.Client
+func CloserHook(closer io.Closer) Hook {
+	return func(ctx context.Context) error {
+		return closer.Close()
+	}
+}
+
+// LoggerHook 刷新并关闭 glog
+func LoggerHook() Hook {
+	return func(ctx context.Context) error {
+		glog.Close()
+		return nil
+	}
+}
@@ -0,0 +1,209 @@
+package concshutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/morehao/golib/glog"
+)
+
+// Hook 关闭钩子，ctx 会在钩子超时后被取消
+type Hook func(ctx context.Context) error
+
+// 常用组件的建议优先级，数值越小越先关闭
+const (
+	PriorityServer    = 0    // 先停止接收新请求
+	PriorityScheduler = 100  // 停止调度新的周期任务
+	PriorityWorker    = 200  // 等待工作池、队列中的任务执行完成
+	PriorityStorage   = 300  // 关闭数据库等存储客户端
+	PriorityLogger    = 1000 // 最后刷新日志
+)
+
+// Manager 优雅关闭协调器，按优先级依次执行注册的关闭钩子
+type Manager struct {
+	mu          sync.Mutex // 用于保护 hooks
+	hooks       []*hookEntry
+	signals     []os.Signal
+	hookTimeout time.Duration // 单个钩子的默认超时时间
+	timeout     time.Duration // 整体关闭的超时时间
+	once        sync.Once
+	result      Result
+}
+
+type hookEntry struct {
+	name     string
+	priority int
+	timeout  time.Duration
+	hook     Hook
+}
+
+// HookResult 单个钩子的执行结果
+type HookResult struct {
+	Name     string
+	Priority int
+	Err      error
+	TimedOut bool
+	Cost     time.Duration
+}
+
+// Result 关闭过程的执行结果，按执行顺序排列
+type Result struct {
+	Hooks []HookResult
+}
+
+// TimedOut 返回超时的钩子名称
+func (r Result) TimedOut() []string {
+	names := make([]string, 0)
+	for _, h := range r.Hooks {
+		if h.TimedOut {
+			names = append(names, h.Name)
+		}
+	}
+	return names
+}
+
+// Err 合并所有钩子返回的错误
+func (r Result) Err() error {
+	errs := make([]error, 0)
+	for _, h := range r.Hooks {
+		if h.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, h.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// New 创建一个新的关闭协调器，默认监听 SIGINT 和 SIGTERM
+func New(options ...Option) *Manager {
+	m := &Manager{
+		signals:     []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		hookTimeout: time.Second * 10,
+		timeout:     time.Second * 30,
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+	return m
+}
+
+// Register 注册关闭钩子，priority 越小越先执行，相同优先级的钩子并发执行，timeout 为0时使用默认超时时间
+func (m *Manager) Register(name string, priority int, timeout time.Duration, hook Hook) {
+	if timeout <= 0 {
+		timeout = m.hookTimeout
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, &hookEntry{
+		name:     name,
+		priority: priority,
+		timeout:  timeout,
+		hook:     hook,
+	})
+}
+
+// Wait 阻塞直到收到退出信号或 ctx 被取消，然后执行关闭流程
+func (m *Manager) Wait(ctx context.Context) Result {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, m.signals...)
+	defer signal.Stop(sigChan)
+
+	select {
+	case sig := <-sigChan:
+		glog.Infof(ctx, "[concshutdown] received signal %s, start shutdown", sig)
+	case <-ctx.Done():
+		glog.Infof(ctx, "[concshutdown] context done, start shutdown")
+	}
+	return m.Shutdown(context.Background())
+}
+
+// Shutdown 按优先级执行所有关闭钩子，只会执行一次，重复调用返回首次的结果
+func (m *Manager) Shutdown(ctx context.Context) Result {
+	m.once.Do(func() {
+		m.result = m.shutdown(ctx)
+	})
+	return m.result
+}
+
+func (m *Manager) shutdown(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	hooks := make([]*hookEntry, len(m.hooks))
+	copy(hooks, m.hooks)
+	m.mu.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority < hooks[j].priority
+	})
+
+	var result Result
+	for start := 0; start < len(hooks); {
+		// 同一优先级的钩子为一组并发执行
+		end := start
+		for end < len(hooks) && hooks[end].priority == hooks[start].priority {
+			end++
+		}
+		group := hooks[start:end]
+		groupResults := make([]HookResult, len(group))
+		var wg sync.WaitGroup
+		for i, h := range group {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				groupResults[i] = runHook(ctx, h)
+			}()
+		}
+		wg.Wait()
+		// 每组执行完立即记录失败，后续的组可能会关闭日志
+		logFailures(ctx, groupResults)
+		result.Hooks = append(result.Hooks, groupResults...)
+		start = end
+	}
+	return result
+}
+
+// logFailures 记录超时和返回错误的钩子
+func logFailures(ctx context.Context, results []HookResult) {
+	for _, h := range results {
+		if h.TimedOut {
+			glog.Warnf(ctx, "[concshutdown] hook %s timed out after %s", h.Name, h.Cost)
+		} else if h.Err != nil {
+			glog.Errorf(ctx, "[concshutdown] hook %s failed: %v", h.Name, h.Err)
+		}
+	}
+}
+
+// runHook 执行单个钩子，超时后不再等待钩子返回
+func runHook(ctx context.Context, h *hookEntry) HookResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("hook panic: %v", r)
+			}
+		}()
+		done <- h.hook(ctx)
+	}()
+
+	res := HookResult{Name: h.name, Priority: h.priority}
+	select {
+	case err := <-done:
+		res.Err = err
+	case <-ctx.Done():
+		res.Err = ctx.Err()
+		res.TimedOut = true
+	}
+	res.Cost = time.Since(start)
+	return res
+}
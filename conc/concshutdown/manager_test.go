@@ -0,0 +1,96 @@
+package concshutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/morehao/golib/conc/concpool"
+	"github.com/morehao/golib/glog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_Shutdown(t *testing.T) {
+	m := New(WithHookTimeout(time.Millisecond * 100))
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Hook {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	pool := concpool.New(2, 10)
+	m.Register("logger", PriorityLogger, 0, record("logger"))
+	m.Register("pool", PriorityWorker, 0, PoolHook(pool))
+	m.Register("server", PriorityServer, 0, record("server"))
+	m.Register("db", PriorityStorage, 0, func(ctx context.Context) error {
+		return errors.New("close db failed")
+	})
+	m.Register("slow", PriorityStorage, 0, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	res := m.Shutdown(context.Background())
+	assert.Equal(t, []string{"server", "logger"}, order)
+	assert.Len(t, res.Hooks, 5)
+	assert.Equal(t, []string{"slow"}, res.TimedOut())
+	assert.ErrorContains(t, res.Err(), "close db failed")
+	assert.False(t, pool.Submit(func(ctx context.Context) error { return nil }))
+
+	// 重复调用返回首次的结果
+	assert.Equal(t, res, m.Shutdown(context.Background()))
+}
+
+func TestManager_Wait(t *testing.T) {
+	m := New(WithSignals(syscall.SIGUSR1))
+	var called bool
+	m.Register("hook", 0, 0, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		_ = syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+	res := m.Wait(context.Background())
+	assert.True(t, called)
+	assert.Nil(t, res.Err())
+}
+
+func TestManager_LogBeforeLoggerHook(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	record := func(message string) string {
+		mu.Lock()
+		events = append(events, message)
+		mu.Unlock()
+		return message
+	}
+	assert.Nil(t, glog.InitLogger(glog.GetDefaultLogConfig(), glog.WithMessageHookFunc(record)))
+	defer func() {
+		assert.Nil(t, glog.InitLogger(glog.GetDefaultLogConfig()))
+	}()
+
+	m := New(WithHookTimeout(time.Millisecond * 100))
+	m.Register("db", PriorityStorage, 0, func(ctx context.Context) error {
+		return errors.New("close db failed")
+	})
+	m.Register("logger", PriorityLogger, 0, func(ctx context.Context) error {
+		record("logger closed")
+		return nil
+	})
+	m.Shutdown(context.Background())
+
+	// 钩子失败的日志在关闭日志之前输出
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"[concshutdown] hook db failed: close db failed", "logger closed"}, events)
+}
@@ -0,0 +1,30 @@
+package concshutdown
+
+import (
+	"os"
+	"time"
+)
+
+// Option 是一个函数类型，用于设置 Manager 的选项
+type Option func(m *Manager)
+
+// WithSignals 设置触发关闭的信号
+func WithSignals(signals ...os.Signal) Option {
+	return func(m *Manager) {
+		m.signals = signals
+	}
+}
+
+// WithHookTimeout 设置单个钩子的默认超时时间
+func WithHookTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.hookTimeout = timeout
+	}
+}
+
+// WithTimeout 设置整个关闭流程的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = timeout
+	}
+}
@@ -0,0 +1,44 @@
+package distlock
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff 重试间隔策略，attempt 为已失败的次数，从1开始
+type Backoff func(attempt int) time.Duration
+
+// defaultBackoff 默认从50ms开始指数退避，最长500ms，并带有50%的随机抖动
+var defaultBackoff = ExponentialBackoff(50*time.Millisecond, 500*time.Millisecond, 0.5)
+
+// ConstantBackoff 固定间隔重试，jitter 为随机抖动比例，取值范围[0, 1]
+func ConstantBackoff(interval time.Duration, jitter float64) Backoff {
+	return func(attempt int) time.Duration {
+		return withJitter(interval, jitter)
+	}
+}
+
+// ExponentialBackoff 指数退避重试，间隔从 base 开始每次翻倍，最长不超过 max，jitter 为随机抖动比例，取值范围[0, 1]
+func ExponentialBackoff(base, max time.Duration, jitter float64) Backoff {
+	return func(attempt int) time.Duration {
+		interval := base
+		for i := 1; i < attempt && interval < max; i++ {
+			interval *= 2
+		}
+		if interval > max {
+			interval = max
+		}
+		return withJitter(interval, jitter)
+	}
+}
+
+// withJitter 在 [d*(1-jitter), d] 范围内随机取值，避免多个实例同时重试
+func withJitter(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || d <= 0 {
+		return d
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return d - time.Duration(jitter*rand.Float64()*float64(d))
+}
@@ -0,0 +1,16 @@
+package distlock
+
+import (
+	"errors"
+)
+
+var (
+	// ErrLockHeld 锁被其他持有者持有
+	ErrLockHeld = errors.New("distlock: lock is held by another owner")
+	// ErrLockTimeout 在等待时间内没有获取到锁
+	ErrLockTimeout = errors.New("distlock: wait for lock timeout")
+	// ErrNotOwner 当前持有者没有持有锁，锁可能已过期或被其他持有者获取
+	ErrNotOwner = errors.New("distlock: lock is not held by current owner")
+	// ErrLockLost 锁已过期或被其他持有者获取
+	ErrLockLost = errors.New("distlock: lock lost")
+)
@@ -0,0 +1,65 @@
+package distlock
+
+import (
+	"context"
+
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// fencingLockScript 获取锁成功时同时递增 fencing token 计数器，保证 token 的大小与获取锁的先后顺序一致
+var fencingLockScript = goredislib.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+// fencingUnlockScript 当前持有者持有锁时删除锁
+var fencingUnlockScript = goredislib.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// fencingRenewalScript 当前持有者持有锁时重置过期时间
+var fencingRenewalScript = goredislib.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// fencingLock 尝试获取一次带 fencing token 的锁
+func (r *RedisStorage) fencingLock(ctx context.Context) (bool, error) {
+	token, err := fencingLockScript.Run(ctx, r.client, []string{r.config.Key, r.fencingKey()}, r.owner, r.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	if token == 0 {
+		return false, nil
+	}
+	r.token = token
+	return true, nil
+}
+
+// fencingUnlock 释放带 fencing token 的锁
+func (r *RedisStorage) fencingUnlock(ctx context.Context) (bool, error) {
+	res, err := fencingUnlockScript.Run(ctx, r.client, []string{r.config.Key}, r.owner).Int64()
+	if err != nil {
+		return false, err
+	}
+	if res == 0 {
+		return false, ErrNotOwner
+	}
+	return true, nil
+}
+
+// fencingRenewal 续期带 fencing token 的锁
+func (r *RedisStorage) fencingRenewal(ctx context.Context) (bool, error) {
+	res, err := fencingRenewalScript.Run(ctx, r.client, []string{r.config.Key}, r.owner, r.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
@@ -2,13 +2,16 @@ package distlock
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/morehao/golib/glog"
 )
 
 // Lock 锁接口（支持不同存储引擎扩展）
-// 暂不考虑可重入性，个人理解，如果存在重复获取锁，通过代码逻辑调整即可实现
+// 默认不可重入，开启 Config.Reentrant 后由存储引擎按持有者记录重入次数
 type Lock interface {
 	Lock(ctx context.Context) (bool, error)
 	Unlock(ctx context.Context) (bool, error)
@@ -20,94 +23,284 @@ type Config struct {
 	AutoRenewal bool          // 是否自动续期
 	TTL         time.Duration // 锁的超时时间
 	Key         string
+	Reentrant   bool   // 是否可重入，相同持有者可以重复获取锁
+	Owner       string // 锁的持有者标识，可重入模式下使用，为空时通过 GenerateOwner 生成
+	Fencing     bool   // 是否在获取锁时生成单调递增的 fencing token
+}
+
+// TryLocker 支持单次尝试获取锁的存储，锁被其他持有者持有时立即返回 false
+type TryLocker interface {
+	TryLock(ctx context.Context) (bool, error)
+}
+
+// FencingLock 支持 fencing token 的锁存储
+// 每次获取锁都会得到比之前更大的 token，下游存储拒绝小于已写入 token 的请求，避免锁过期后的旧持有者覆盖数据
+type FencingLock interface {
+	Lock
+	FencingToken() int64
 }
 
 type DistLock struct {
-	store  Lock
-	config *Config
-	// count    int          // 可重入计数器
-	mu       sync.RWMutex // 使用 RWMutex 提高并发读性能
-	stopOnce sync.Once    // 确保 stopChan 只关闭一次
+	store    Lock
+	config   *Config
+	onLost   func(key string, err error) // 锁丢失时的回调函数
+	backoff  Backoff                     // LockWithWait 的重试间隔策略
+	count    int                         // 当前实例持有锁的次数，归零时停止续期
+	token    int64                       // 本次持有锁的 fencing token
+	mu       sync.RWMutex                // 使用 RWMutex 提高并发读性能
 	stopChan chan struct{}
+	lostChan chan struct{} // 本次持有期间锁丢失时关闭
+	// 未开启自动续期时，重入获取或部分释放刷新了存储中的过期时间，通知过期监控重新计时
+	extendChan chan struct{}
+}
+
+// Option 是一个函数类型，用于设置 DistLock 的选项
+type Option func(dl *DistLock)
+
+// WithOnLost 设置锁丢失时的回调函数，err 为续期失败的原因
+func WithOnLost(fn func(key string, err error)) Option {
+	return func(dl *DistLock) {
+		dl.onLost = fn
+	}
+}
+
+// WithBackoff 设置 LockWithWait 的重试间隔策略
+func WithBackoff(backoff Backoff) Option {
+	return func(dl *DistLock) {
+		dl.backoff = backoff
+	}
 }
 
 // NewDistLock 创建新锁实例
-func NewDistLock(store Lock, config *Config) *DistLock {
-	return &DistLock{
-		store:    store,
-		config:   config,
-		stopChan: make(chan struct{}),
+func NewDistLock(store Lock, config *Config, options ...Option) *DistLock {
+	dl := &DistLock{
+		store:   store,
+		config:  config,
+		backoff: defaultBackoff,
+	}
+	for _, opt := range options {
+		opt(dl)
 	}
+	return dl
 }
 
+// Lock 获取锁，等待策略由存储引擎决定，锁被其他持有者持有时返回 ErrLockHeld
 func (dl *DistLock) Lock(ctx context.Context) (bool, error) {
+	return dl.acquire(ctx, dl.store.Lock)
+}
+
+// TryLock 只尝试获取一次锁，不等待，锁被其他持有者持有时返回 ErrLockHeld
+func (dl *DistLock) TryLock(ctx context.Context) (bool, error) {
+	tl, ok := dl.store.(TryLocker)
+	if !ok {
+		return false, fmt.Errorf("lock storage does not support TryLock")
+	}
+	return dl.acquire(ctx, tl.TryLock)
+}
+
+// LockWithWait 按重试间隔策略反复尝试获取锁，最多等待 maxWait，超时返回 ErrLockTimeout
+// 存储引擎返回除 ErrLockHeld 以外的错误时立即返回，便于区分锁竞争和存储故障
+func (dl *DistLock) LockWithWait(ctx context.Context, maxWait time.Duration) (bool, error) {
+	deadline := time.Now().Add(maxWait)
+	for attempt := 1; ; attempt++ {
+		ok, err := dl.TryLock(ctx)
+		if !errors.Is(err, ErrLockHeld) {
+			return ok, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, ErrLockTimeout
+		}
+		wait := dl.backoff(attempt)
+		if wait > remaining {
+			wait = remaining
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// acquire 通过 lockFn 获取锁，并在首次获取时启动续期
+func (dl *DistLock) acquire(ctx context.Context, lockFn func(ctx context.Context) (bool, error)) (bool, error) {
 	dl.mu.Lock()
 	defer dl.mu.Unlock()
 
-	// 可重入检查
-	// if dl.count > 0 {
-	// 	dl.count++
-	// 	return true, nil
-	// }
-
-	// 获取新锁
-	ok, err := dl.store.Lock(ctx)
+	// 获取锁，可重入模式下由存储引擎累加重入次数
+	ok, err := lockFn(ctx)
 	if err != nil {
 		return false, err
 	}
 	if !ok {
-		return false, fmt.Errorf("lock acquisition failed")
+		return false, ErrLockHeld
 	}
 
-	// dl.count = 1
+	dl.count++
+	if fl, ok := dl.store.(FencingLock); ok {
+		dl.token = fl.FencingToken()
+	}
 
-	// 启动自动续期
-	if dl.config.AutoRenewal {
-		go dl.autoRenewal(ctx)
+	// 首次获取时启动续期或过期监控
+	if dl.count == 1 {
+		dl.stopChan = make(chan struct{})
+		dl.lostChan = make(chan struct{})
+		if dl.config.AutoRenewal {
+			go dl.autoRenewal(ctx, dl.stopChan, dl.lostChan)
+		} else {
+			dl.extendChan = make(chan struct{}, 1)
+			go dl.watchExpiry(dl.stopChan, dl.lostChan, dl.extendChan)
+		}
+	} else {
+		dl.extendExpiry()
 	}
 
 	return true, nil
 }
 
+// Unlock 释放锁，未持有锁时返回 ErrNotOwner
 func (dl *DistLock) Unlock(ctx context.Context) (bool, error) {
 	dl.mu.Lock()
 	defer dl.mu.Unlock()
 
-	// if dl.count == 0 {
-	// 	return false, fmt.Errorf("lock not held")
-	// }
-	//
-	// dl.count--
-	// if dl.count > 0 {
-	// 	return true, nil
-	// }
+	if dl.count == 0 {
+		return false, ErrNotOwner
+	}
+
+	// 完全释放时停止续期
+	dl.count--
+	if dl.count == 0 {
+		close(dl.stopChan)
+		dl.token = 0
+	}
 
-	// 停止续期
-	if dl.config.AutoRenewal {
-		dl.stopOnce.Do(func() {
-			close(dl.stopChan)
-		})
+	// 释放锁，可重入模式下重入次数归零时才真正删除
+	ok, err := dl.store.Unlock(ctx)
+	if err == nil && dl.count > 0 {
+		dl.extendExpiry()
 	}
+	return ok, err
+}
 
-	// 释放锁
-	return dl.store.Unlock(ctx)
+// LockWithToken 获取锁并返回 fencing token，存储引擎需实现 FencingLock 并开启 Config.Fencing
+func (dl *DistLock) LockWithToken(ctx context.Context) (int64, error) {
+	if _, ok := dl.store.(FencingLock); !ok || !dl.config.Fencing {
+		return 0, fmt.Errorf("fencing token is not enabled")
+	}
+	if _, err := dl.Lock(ctx); err != nil {
+		return 0, err
+	}
+	return dl.FencingToken(), nil
 }
 
-// 自动续期循环
-func (dl *DistLock) autoRenewal(ctx context.Context) {
+// FencingToken 返回本次持有锁的 fencing token，未持有锁或未开启 fencing 时返回0
+func (dl *DistLock) FencingToken() int64 {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+	return dl.token
+}
+
+// Lost 返回本次持有期间锁丢失时关闭的 channel，未持有锁时返回已关闭的 channel
+// 开启自动续期时续期失败即视为丢失，否则在 TTL 到期后视为丢失
+func (dl *DistLock) Lost() <-chan struct{} {
+	dl.mu.RLock()
+	defer dl.mu.RUnlock()
+
+	if dl.count == 0 {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	return dl.lostChan
+}
+
+// Context 返回锁丢失时被取消的上下文，取消原因为 ErrLockLost，临界区代码可以据此及时停止
+func (dl *DistLock) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	lost := dl.Lost()
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-lost:
+			cancel(ErrLockLost)
+		case <-lockCtx.Done():
+		}
+	}()
+	return lockCtx, func() {
+		cancel(context.Canceled)
+	}
+}
+
+// 自动续期循环，续期出错时缩短间隔重试，直到锁过期仍未成功则视为丢失
+func (dl *DistLock) autoRenewal(ctx context.Context, stopChan, lostChan chan struct{}) {
 	renewalInterval := dl.config.TTL / 2
-	ticker := time.NewTicker(renewalInterval)
-	defer ticker.Stop()
+	retryInterval := dl.config.TTL / 10
+	lastRenewed := time.Now()
+	timer := time.NewTimer(renewalInterval)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			ok, err := dl.store.Renewal(ctx)
-			if err != nil || !ok {
-				return
+			if err == nil && ok {
+				lastRenewed = time.Now()
+				timer.Reset(renewalInterval)
+				continue
 			}
-		case <-dl.stopChan:
+			if err == nil {
+				// 锁已过期或被其他持有者获取
+				err = ErrLockLost
+			} else if time.Since(lastRenewed)+retryInterval < dl.config.TTL {
+				glog.Warnf(ctx, "[distlock] renewal lock %s failed, retry later: %v", dl.config.Key, err)
+				timer.Reset(retryInterval)
+				continue
+			}
+			glog.Errorf(ctx, "[distlock] lock %s lost: %v", dl.config.Key, err)
+			dl.lost(lostChan, err)
+			return
+		case <-stopChan:
 			return
 		}
 	}
 }
+
+// extendExpiry 可重入模式下存储引擎在重入获取和部分释放时刷新过期时间，过期监控需要从此刻重新计时
+func (dl *DistLock) extendExpiry() {
+	if dl.extendChan == nil {
+		return
+	}
+	select {
+	case dl.extendChan <- struct{}{}:
+	default:
+	}
+}
+
+// watchExpiry 未开启自动续期时，在距离最近一次获取锁 TTL 后视为锁丢失
+func (dl *DistLock) watchExpiry(stopChan, lostChan, extendChan chan struct{}) {
+	timer := time.NewTimer(dl.config.TTL)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			dl.lost(lostChan, ErrLockLost)
+			return
+		case <-extendChan:
+			timer.Reset(dl.config.TTL)
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// lost 通知锁已丢失
+func (dl *DistLock) lost(lostChan chan struct{}, err error) {
+	close(lostChan)
+	if dl.onLost != nil {
+		dl.onLost(dl.config.Key, err)
+	}
+}
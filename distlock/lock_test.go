@@ -43,3 +43,439 @@ func TestLock(t *testing.T) {
 	t.Log("unlockRes result: ", unlockRes)
 
 }
+
+func TestReentrantLock(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	config := Config{
+		Key:         "test_reentrant_lock",
+		TTL:         time.Second * 5,
+		AutoRenewal: true,
+		Reentrant:   true,
+	}
+	redisStore := NewRedisStorage(rdbClient, config)
+	lock := NewDistLock(redisStore, &config)
+	ctx := context.Background()
+
+	// 同一持有者可以重复获取锁
+	firstLockRes, firstLockErr := lock.Lock(ctx)
+	assert.Nil(t, firstLockErr)
+	assert.True(t, firstLockRes)
+	secondLockRes, secondLockErr := lock.Lock(ctx)
+	assert.Nil(t, secondLockErr)
+	assert.True(t, secondLockRes)
+
+	// 相同 Owner 的其他锁实例也可以重入
+	sameOwnerConfig := config
+	sameOwnerConfig.Owner = redisStore.Owner()
+	sameOwnerLock := NewDistLock(NewRedisStorage(rdbClient, sameOwnerConfig), &sameOwnerConfig)
+	sameOwnerRes, sameOwnerErr := sameOwnerLock.Lock(ctx)
+	assert.Nil(t, sameOwnerErr)
+	assert.True(t, sameOwnerRes)
+	_, sameOwnerUnlockErr := sameOwnerLock.Unlock(ctx)
+	assert.Nil(t, sameOwnerUnlockErr)
+
+	// 其他持有者无法获取锁
+	otherConfig := config
+	otherStore := NewRedisStorage(rdbClient, otherConfig)
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*500)
+	defer cancel()
+	otherLockRes, _ := otherStore.Lock(timeoutCtx)
+	assert.False(t, otherLockRes)
+
+	// 重入次数归零后才真正释放
+	_, unlockErr := lock.Unlock(ctx)
+	assert.Nil(t, unlockErr)
+	assert.Equal(t, int64(1), rdbClient.Exists(ctx, config.Key).Val())
+	_, unlockErr = lock.Unlock(ctx)
+	assert.Nil(t, unlockErr)
+	assert.Equal(t, int64(0), rdbClient.Exists(ctx, config.Key).Val())
+
+	_, notHeldErr := lock.Unlock(ctx)
+	assert.NotNil(t, notHeldErr)
+}
+
+// renewalFailStore 续期总是失败的存储，用于模拟锁丢失
+type renewalFailStore struct{}
+
+func (renewalFailStore) Lock(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (renewalFailStore) Unlock(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (renewalFailStore) Renewal(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+func TestLockLost(t *testing.T) {
+	config := Config{
+		Key:         "test_lost_lock",
+		TTL:         time.Millisecond * 200,
+		AutoRenewal: true,
+	}
+	var lostKey string
+	lostCh := make(chan error, 1)
+	lock := NewDistLock(renewalFailStore{}, &config, WithOnLost(func(key string, err error) {
+		lostKey = key
+		lostCh <- err
+	}))
+	ctx := context.Background()
+
+	ok, err := lock.Lock(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	lockCtx, cancel := lock.Context(ctx)
+	defer cancel()
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("锁丢失后应收到通知")
+	}
+	<-lockCtx.Done()
+	assert.ErrorIs(t, context.Cause(lockCtx), ErrLockLost)
+	assert.ErrorIs(t, <-lostCh, ErrLockLost)
+	assert.Equal(t, config.Key, lostKey)
+
+	_, unlockErr := lock.Unlock(ctx)
+	assert.Nil(t, unlockErr)
+
+	// 重新获取锁后使用新的通知 channel
+	_, err = lock.Lock(ctx)
+	assert.Nil(t, err)
+	select {
+	case <-lock.Lost():
+		t.Fatal("新的持有周期不应立即收到丢失通知")
+	default:
+	}
+	_, _ = lock.Unlock(ctx)
+}
+
+func TestReentrantLockExpiry(t *testing.T) {
+	config := Config{
+		Key:       "test_reentrant_expiry_" + GenerateOwner(),
+		TTL:       time.Millisecond * 300,
+		Reentrant: true,
+	}
+	lock := NewDistLock(NewMemoryStorage(config), &config)
+	ctx := context.Background()
+
+	ok, err := lock.Lock(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	time.Sleep(time.Millisecond * 200)
+
+	// 重入获取刷新了过期时间，首次获取的 TTL 到期后锁仍然有效
+	ok, err = lock.TryLock(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	time.Sleep(time.Millisecond * 200)
+	select {
+	case <-lock.Lost():
+		t.Fatal("重入获取后不应按首次获取的 TTL 收到丢失通知")
+	default:
+	}
+
+	// 部分释放同样刷新过期时间
+	_, err = lock.Unlock(ctx)
+	assert.Nil(t, err)
+	time.Sleep(time.Millisecond * 200)
+	select {
+	case <-lock.Lost():
+		t.Fatal("部分释放后不应按重入获取的 TTL 收到丢失通知")
+	default:
+	}
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("锁过期后应收到通知")
+	}
+	_, err = lock.Unlock(ctx)
+	assert.ErrorIs(t, err, ErrNotOwner)
+}
+
+func TestFencingLock(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	config := Config{
+		Key:     "test_fencing_lock",
+		TTL:     time.Second * 5,
+		Fencing: true,
+	}
+	ctx := context.Background()
+
+	// 每次获取锁得到的 token 单调递增
+	var lastToken int64
+	for i := 0; i < 3; i++ {
+		lock := NewDistLock(NewRedisStorage(rdbClient, config), &config)
+		token, err := lock.LockWithToken(ctx)
+		assert.Nil(t, err)
+		assert.Greater(t, token, lastToken)
+		lastToken = token
+		_, unlockErr := lock.Unlock(ctx)
+		assert.Nil(t, unlockErr)
+		assert.Equal(t, int64(0), lock.FencingToken())
+	}
+
+	// 可重入模式下重入时 token 不变
+	reentrantConfig := config
+	reentrantConfig.Reentrant = true
+	lock := NewDistLock(NewRedisStorage(rdbClient, reentrantConfig), &reentrantConfig)
+	firstToken, err := lock.LockWithToken(ctx)
+	assert.Nil(t, err)
+	assert.Greater(t, firstToken, lastToken)
+	secondToken, err := lock.LockWithToken(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, firstToken, secondToken)
+	_, _ = lock.Unlock(ctx)
+	_, _ = lock.Unlock(ctx)
+
+	// 未开启 fencing 时返回错误
+	plainConfig := Config{Key: "test_plain_lock", TTL: time.Second}
+	_, plainErr := NewDistLock(NewRedisStorage(rdbClient, plainConfig), &plainConfig).LockWithToken(ctx)
+	assert.NotNil(t, plainErr)
+}
+
+func TestTryLockAndLockWithWait(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	ctx := context.Background()
+	configs := map[string]Config{
+		"redsync":   {Key: "test_try_lock", TTL: time.Second},
+		"reentrant": {Key: "test_try_lock_reentrant", TTL: time.Second, Reentrant: true},
+		"fencing":   {Key: "test_try_lock_fencing", TTL: time.Second, Fencing: true},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			holder := NewDistLock(NewRedisStorage(rdbClient, config), &config)
+			ok, err := holder.TryLock(ctx)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+
+			contender := NewDistLock(NewRedisStorage(rdbClient, config), &config,
+				WithBackoff(ConstantBackoff(time.Millisecond*20, 0.5)))
+			start := time.Now()
+			ok, err = contender.TryLock(ctx)
+			assert.False(t, ok)
+			assert.ErrorIs(t, err, ErrLockHeld)
+			assert.Less(t, time.Since(start), time.Millisecond*100)
+
+			ok, err = contender.LockWithWait(ctx, time.Millisecond*100)
+			assert.False(t, ok)
+			assert.ErrorIs(t, err, ErrLockTimeout)
+
+			// 持有者释放后可以在等待时间内获取到锁
+			go func() {
+				time.Sleep(time.Millisecond * 50)
+				_, _ = holder.Unlock(ctx)
+			}()
+			ok, err = contender.LockWithWait(ctx, time.Second)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+
+			// 锁已被其他持有者获取时，原持有者释放返回 ErrNotOwner
+			_, err = holder.Unlock(ctx)
+			assert.ErrorIs(t, err, ErrNotOwner)
+			_, err = NewRedisStorage(rdbClient, config).Unlock(ctx)
+			assert.ErrorIs(t, err, ErrNotOwner)
+
+			_, err = contender.Unlock(ctx)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Millisecond*10, time.Millisecond*50, 0)
+	assert.Equal(t, time.Millisecond*10, backoff(1))
+	assert.Equal(t, time.Millisecond*20, backoff(2))
+	assert.Equal(t, time.Millisecond*40, backoff(3))
+	assert.Equal(t, time.Millisecond*50, backoff(4))
+
+	jitterBackoff := ConstantBackoff(time.Millisecond*100, 0.5)
+	for i := 1; i < 10; i++ {
+		d := jitterBackoff(i)
+		assert.GreaterOrEqual(t, d, time.Millisecond*50)
+		assert.LessOrEqual(t, d, time.Millisecond*100)
+	}
+}
+
+func TestRWLock(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	config := Config{
+		Key:         "test_rw_lock",
+		TTL:         time.Second * 2,
+		AutoRenewal: true,
+	}
+	ctx := context.Background()
+
+	t.Run("readers share and writer excludes", func(t *testing.T) {
+		reader1 := NewRWLock(rdbClient, config)
+		reader2 := NewRWLock(rdbClient, config)
+		writer := NewRWLock(rdbClient, config)
+
+		ok, err := reader1.RLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		ok, err = reader2.TryRLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		// 读者持有时写者无法获取锁
+		_, err = writer.TryLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		// 自动续期后读锁仍然有效
+		time.Sleep(time.Second * 3)
+		_, err = writer.TryLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		_, err = reader1.RUnlock(ctx)
+		assert.Nil(t, err)
+		_, err = reader2.RUnlock(ctx)
+		assert.Nil(t, err)
+
+		ok, err = writer.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		// 写者持有时读者无法获取锁
+		_, err = reader1.TryRLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+		_, err = writer.Unlock(ctx)
+		assert.Nil(t, err)
+		_, err = writer.Unlock(ctx)
+		assert.ErrorIs(t, err, ErrNotOwner)
+	})
+
+	t.Run("writer preference", func(t *testing.T) {
+		reader := NewRWLock(rdbClient, config)
+		lateReader := NewRWLock(rdbClient, config)
+		writer := NewRWLock(rdbClient, config)
+
+		ok, err := reader.RLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		writeDone := make(chan error, 1)
+		go func() {
+			_, err := writer.Lock(ctx)
+			writeDone <- err
+		}()
+		time.Sleep(time.Millisecond * 500)
+
+		// 写者等待期间新的读者无法获取锁
+		_, err = lateReader.TryRLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		_, err = reader.RUnlock(ctx)
+		assert.Nil(t, err)
+		select {
+		case err := <-writeDone:
+			assert.Nil(t, err)
+		case <-time.After(time.Second * 3):
+			t.Fatal("writer did not acquire lock after readers released")
+		}
+		_, err = writer.Unlock(ctx)
+		assert.Nil(t, err)
+
+		ok, err = lateReader.TryRLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = lateReader.RUnlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("expired reader does not block writer", func(t *testing.T) {
+		expiringConfig := config
+		expiringConfig.AutoRenewal = false
+		expiringConfig.TTL = time.Millisecond * 500
+		reader := NewRWLock(rdbClient, expiringConfig)
+		writer := NewRWLock(rdbClient, expiringConfig)
+
+		ok, err := reader.RLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		<-reader.ReadLock().Lost()
+
+		ok, err = writer.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = writer.Unlock(ctx)
+		assert.Nil(t, err)
+		_, err = reader.RUnlock(ctx)
+		assert.ErrorIs(t, err, ErrNotOwner)
+	})
+}
+
+func TestSemaphore(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	config := Config{
+		Key:         "test_semaphore_" + GenerateOwner(),
+		TTL:         time.Second,
+		AutoRenewal: true,
+	}
+	ctx := context.Background()
+
+	first := NewSemaphore(rdbClient, config, 2)
+	second := NewSemaphore(rdbClient, config, 2)
+	third := NewSemaphore(rdbClient, config, 2)
+
+	ok, err := first.Acquire(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = second.TryAcquire(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 许可用完后无法获取，续期后租约仍然有效
+	time.Sleep(time.Millisecond * 1500)
+	_, err = third.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrLockHeld)
+	_, err = third.AcquireWithWait(ctx, time.Millisecond*300)
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	holders, err := first.Holders(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), holders)
+
+	_, err = first.Release(ctx)
+	assert.Nil(t, err)
+	ok, err = third.TryAcquire(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	_, err = first.Release(ctx)
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	_, err = second.Release(ctx)
+	assert.Nil(t, err)
+	_, err = third.Release(ctx)
+	assert.Nil(t, err)
+
+	// 未续期的租约过期后释放许可
+	expiringConfig := config
+	expiringConfig.TTL = time.Millisecond * 500
+	expiringConfig.AutoRenewal = false
+	crashed := NewSemaphore(rdbClient, expiringConfig, 1)
+	other := NewSemaphore(rdbClient, expiringConfig, 1)
+	ok, err = crashed.TryAcquire(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	_, err = other.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrLockHeld)
+	<-crashed.Lost()
+	ok, err = other.TryAcquire(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	_, err = other.Release(ctx)
+	assert.Nil(t, err)
+}
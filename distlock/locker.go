@@ -0,0 +1,217 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
+	goredislib "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// StorageFactory 根据锁配置创建存储引擎
+type StorageFactory func(config Config) Lock
+
+// Locker 锁工厂，为任意 key 创建锁，所有锁共享同一个存储客户端和默认配置
+type Locker struct {
+	newStore StorageFactory
+	config   Config // 默认锁配置，Key 字段不生效
+	prefix   string
+	options  []Option
+}
+
+// LockerOption 是一个函数类型，用于设置 Locker 的选项
+type LockerOption func(l *Locker)
+
+// WithKeyPrefix 设置 key 前缀，锁的 key 为 prefix:key
+func WithKeyPrefix(prefix string) LockerOption {
+	return func(l *Locker) {
+		l.prefix = prefix
+	}
+}
+
+// WithLockOptions 设置创建锁时使用的 DistLock 选项
+func WithLockOptions(options ...Option) LockerOption {
+	return func(l *Locker) {
+		l.options = append(l.options, options...)
+	}
+}
+
+// NewLocker 创建锁工厂，config 为默认锁配置
+func NewLocker(newStore StorageFactory, config Config, options ...LockerOption) *Locker {
+	l := &Locker{
+		newStore: newStore,
+		config:   config,
+	}
+	for _, opt := range options {
+		opt(l)
+	}
+	return l
+}
+
+// NewRedisLocker 创建基于 Redis 的锁工厂，所有锁共享 client 的连接池
+func NewRedisLocker(client goredislib.UniversalClient, config Config, options ...LockerOption) *Locker {
+	rs := redsync.New(goredis.NewPool(client))
+	return NewLocker(func(config Config) Lock {
+		return newRedisStorage(client, rs, config)
+	}, config, options...)
+}
+
+// NewMysqlLocker 创建基于 MySQL 的锁工厂
+func NewMysqlLocker(db *gorm.DB, config Config, options ...LockerOption) *Locker {
+	return NewLocker(func(config Config) Lock {
+		return NewMysqlStorage(db, config)
+	}, config, options...)
+}
+
+// NewMemoryLocker 创建基于进程内存的锁工厂
+func NewMemoryLocker(config Config, options ...LockerOption) *Locker {
+	return NewLocker(func(config Config) Lock {
+		return NewMemoryStorage(config)
+	}, config, options...)
+}
+
+// ObtainOption 是一个函数类型，用于设置单次获取锁的选项
+type ObtainOption func(o *obtainOptions)
+
+type obtainOptions struct {
+	ttl         time.Duration
+	autoRenewal *bool
+	reentrant   *bool
+	fencing     *bool
+	owner       string
+	noWait      bool
+	maxWait     time.Duration
+}
+
+// WithTTL 设置锁的超时时间，覆盖默认配置
+func WithTTL(ttl time.Duration) ObtainOption {
+	return func(o *obtainOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithAutoRenewal 设置是否自动续期，覆盖默认配置
+func WithAutoRenewal(autoRenewal bool) ObtainOption {
+	return func(o *obtainOptions) {
+		o.autoRenewal = &autoRenewal
+	}
+}
+
+// WithReentrant 设置是否可重入，覆盖默认配置
+func WithReentrant(reentrant bool) ObtainOption {
+	return func(o *obtainOptions) {
+		o.reentrant = &reentrant
+	}
+}
+
+// WithFencing 设置是否生成 fencing token，覆盖默认配置
+func WithFencing(fencing bool) ObtainOption {
+	return func(o *obtainOptions) {
+		o.fencing = &fencing
+	}
+}
+
+// WithOwner 设置锁的持有者标识，覆盖默认配置
+func WithOwner(owner string) ObtainOption {
+	return func(o *obtainOptions) {
+		o.owner = owner
+	}
+}
+
+// WithNoWait 只尝试获取一次锁，锁被其他持有者持有时立即返回 ErrLockHeld
+func WithNoWait() ObtainOption {
+	return func(o *obtainOptions) {
+		o.noWait = true
+	}
+}
+
+// WithMaxWait 按 Backoff 策略重试获取锁，最多等待 maxWait，超时返回 ErrLockTimeout
+func WithMaxWait(maxWait time.Duration) ObtainOption {
+	return func(o *obtainOptions) {
+		o.maxWait = maxWait
+	}
+}
+
+// NewLock 为 key 创建锁，不获取锁
+func (l *Locker) NewLock(key string, options ...ObtainOption) *DistLock {
+	config, _ := l.buildConfig(key, options)
+	return NewDistLock(l.newStore(config), &config, l.options...)
+}
+
+// Obtain 为 key 创建锁并获取，默认等待策略由存储引擎决定，可通过 WithNoWait、WithMaxWait 修改
+func (l *Locker) Obtain(ctx context.Context, key string, options ...ObtainOption) (*DistLock, error) {
+	config, o := l.buildConfig(key, options)
+	dl := NewDistLock(l.newStore(config), &config, l.options...)
+
+	var err error
+	switch {
+	case o.noWait:
+		_, err = dl.TryLock(ctx)
+	case o.maxWait > 0:
+		_, err = dl.LockWithWait(ctx, o.maxWait)
+	default:
+		_, err = dl.Lock(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
+
+// WithLock 获取 key 的锁并自动续期，执行 fn 后释放锁
+// fn 的 ctx 会在锁丢失时取消，锁在 fn 执行期间丢失且 fn 没有返回错误时返回 ErrLockLost
+func (l *Locker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, options ...ObtainOption) error {
+	options = append(options, WithAutoRenewal(true))
+	dl, err := l.Obtain(ctx, key, options...)
+	if err != nil {
+		return err
+	}
+
+	lockCtx, cancel := dl.Context(ctx)
+	defer cancel()
+	fnErr := fn(lockCtx)
+	lost := errors.Is(context.Cause(lockCtx), ErrLockLost)
+
+	// ctx 取消后仍需释放锁
+	_, unlockErr := dl.Unlock(context.WithoutCancel(ctx))
+	if fnErr != nil {
+		return fnErr
+	}
+	if lost {
+		return ErrLockLost
+	}
+	return unlockErr
+}
+
+// buildConfig 合并默认配置和单次获取锁的选项
+func (l *Locker) buildConfig(key string, options []ObtainOption) (Config, obtainOptions) {
+	var o obtainOptions
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	config := l.config
+	config.Key = key
+	if l.prefix != "" {
+		config.Key = l.prefix + ":" + key
+	}
+	if o.ttl > 0 {
+		config.TTL = o.ttl
+	}
+	if o.autoRenewal != nil {
+		config.AutoRenewal = *o.autoRenewal
+	}
+	if o.reentrant != nil {
+		config.Reentrant = *o.reentrant
+	}
+	if o.fencing != nil {
+		config.Fencing = *o.fencing
+	}
+	if o.owner != "" {
+		config.Owner = o.owner
+	}
+	return config, o
+}
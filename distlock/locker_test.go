@@ -0,0 +1,77 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocker(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	lockers := map[string]*Locker{
+		"redis":  NewRedisLocker(rdbClient, Config{TTL: time.Second * 2}, WithKeyPrefix("test_locker")),
+		"memory": NewMemoryLocker(Config{TTL: time.Second * 2}, WithKeyPrefix("test_locker")),
+	}
+	ctx := context.Background()
+
+	for name, locker := range lockers {
+		t.Run(name, func(t *testing.T) {
+			orderID := "order_" + GenerateOwner()
+			dl, err := locker.Obtain(ctx, orderID)
+			assert.Nil(t, err)
+
+			// 相同 key 互斥，不同 key 互不影响
+			_, err = locker.Obtain(ctx, orderID, WithNoWait())
+			assert.ErrorIs(t, err, ErrLockHeld)
+			_, err = locker.Obtain(ctx, orderID, WithMaxWait(time.Millisecond*300))
+			assert.ErrorIs(t, err, ErrLockTimeout)
+			other, err := locker.Obtain(ctx, orderID+"_other", WithNoWait())
+			assert.Nil(t, err)
+			_, err = other.Unlock(ctx)
+			assert.Nil(t, err)
+
+			_, err = dl.Unlock(ctx)
+			assert.Nil(t, err)
+
+			// WithLock 执行期间持有锁，执行完成后释放
+			called := false
+			err = locker.WithLock(ctx, orderID, func(ctx context.Context) error {
+				called = true
+				_, heldErr := locker.Obtain(ctx, orderID, WithNoWait())
+				assert.ErrorIs(t, heldErr, ErrLockHeld)
+				return nil
+			})
+			assert.Nil(t, err)
+			assert.True(t, called)
+
+			fnErr := errors.New("fn failed")
+			err = locker.WithLock(ctx, orderID, func(ctx context.Context) error {
+				return fnErr
+			})
+			assert.ErrorIs(t, err, fnErr)
+
+			dl, err = locker.Obtain(ctx, orderID, WithNoWait())
+			assert.Nil(t, err)
+			_, err = dl.Unlock(ctx)
+			assert.Nil(t, err)
+		})
+	}
+
+	t.Run("key prefix", func(t *testing.T) {
+		key := GenerateOwner()
+		dl, err := lockers["memory"].Obtain(ctx, key)
+		assert.Nil(t, err)
+		config := Config{Key: "test_locker:" + key, TTL: time.Second}
+		ok, err := NewMemoryStorage(config).TryLock(ctx)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		_, err = dl.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+}
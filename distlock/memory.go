@@ -0,0 +1,136 @@
+package distlock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryLocks 进程内共享的锁状态，相同 key 的 MemoryStorage 互斥
+var memoryLocks = &memoryLockTable{
+	locks:  make(map[string]*memoryLockEntry),
+	tokens: make(map[string]int64),
+}
+
+type memoryLockTable struct {
+	mu     sync.Mutex
+	locks  map[string]*memoryLockEntry
+	tokens map[string]int64 // fencing token 计数器，释放锁后保留以保证单调递增
+}
+
+type memoryLockEntry struct {
+	owner    string
+	count    int64
+	token    int64
+	expireAt time.Time
+}
+
+// MemoryStorage 是基于进程内存实现的 LockStorage，适用于测试和单实例部署
+type MemoryStorage struct {
+	config Config
+	owner  string
+	token  int64 // 最近一次获取锁得到的 fencing token
+}
+
+// NewMemoryStorage 创建一个新的 MemoryStorage 实例
+func NewMemoryStorage(config Config) *MemoryStorage {
+	owner := config.Owner
+	if owner == "" {
+		owner = GenerateOwner()
+	}
+	return &MemoryStorage{
+		config: config,
+		owner:  owner,
+	}
+}
+
+// Owner 返回锁的持有者标识
+func (m *MemoryStorage) Owner() string {
+	return m.owner
+}
+
+// Lock 获取锁，锁被其他持有者持有时随机间隔重试
+func (m *MemoryStorage) Lock(ctx context.Context) (bool, error) {
+	return retryLock(ctx, m.TryLock)
+}
+
+// TryLock 只尝试获取一次锁，不重试
+func (m *MemoryStorage) TryLock(ctx context.Context) (bool, error) {
+	memoryLocks.mu.Lock()
+	defer memoryLocks.mu.Unlock()
+
+	entry := memoryLocks.held(m.config.Key)
+	if entry != nil {
+		if entry.owner != m.owner || !m.config.Reentrant {
+			return false, nil
+		}
+		entry.count++
+		entry.expireAt = time.Now().Add(m.config.TTL)
+		if m.config.Fencing {
+			m.token = entry.token
+		}
+		return true, nil
+	}
+
+	entry = &memoryLockEntry{
+		owner:    m.owner,
+		count:    1,
+		expireAt: time.Now().Add(m.config.TTL),
+	}
+	if m.config.Fencing {
+		memoryLocks.tokens[m.config.Key]++
+		entry.token = memoryLocks.tokens[m.config.Key]
+		m.token = entry.token
+	}
+	memoryLocks.locks[m.config.Key] = entry
+	return true, nil
+}
+
+// Unlock 释放锁，可重入模式下重入次数归零时才真正释放
+func (m *MemoryStorage) Unlock(ctx context.Context) (bool, error) {
+	memoryLocks.mu.Lock()
+	defer memoryLocks.mu.Unlock()
+
+	entry := memoryLocks.held(m.config.Key)
+	if entry == nil || entry.owner != m.owner {
+		return false, ErrNotOwner
+	}
+	entry.count--
+	if entry.count > 0 {
+		entry.expireAt = time.Now().Add(m.config.TTL)
+		return true, nil
+	}
+	delete(memoryLocks.locks, m.config.Key)
+	return true, nil
+}
+
+// Renewal 锁续期
+func (m *MemoryStorage) Renewal(ctx context.Context) (bool, error) {
+	memoryLocks.mu.Lock()
+	defer memoryLocks.mu.Unlock()
+
+	entry := memoryLocks.held(m.config.Key)
+	if entry == nil || entry.owner != m.owner {
+		return false, nil
+	}
+	entry.expireAt = time.Now().Add(m.config.TTL)
+	return true, nil
+}
+
+// FencingToken 返回最近一次获取锁得到的 fencing token，未开启 fencing 时返回0
+func (m *MemoryStorage) FencingToken() int64 {
+	return m.token
+}
+
+// held 返回未过期的锁，已过期的锁会被清理
+func (t *memoryLockTable) held(key string) *memoryLockEntry {
+	entry, ok := t.locks[key]
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(entry.expireAt) {
+		delete(t.locks, key)
+		return nil
+	}
+	return entry
+}
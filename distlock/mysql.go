@@ -0,0 +1,151 @@
+package distlock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// mysqlLockRecord 锁记录，释放锁时保留记录以保证 fencing token 单调递增
+type mysqlLockRecord struct {
+	LockKey  string    `gorm:"column:lock_key;type:varchar(191);primaryKey"`
+	Owner    string    `gorm:"column:owner;type:varchar(128);not null;default:''"`
+	Count    int64     `gorm:"column:count;not null;default:0"`
+	Token    int64     `gorm:"column:token;not null;default:0"`
+	ExpireAt time.Time `gorm:"column:expire_at;type:datetime(3);not null"`
+}
+
+// mysqlLockTable 锁表的表名
+const mysqlLockTable = "distlock"
+
+func (mysqlLockRecord) TableName() string {
+	return mysqlLockTable
+}
+
+// InitMysqlLockTable 创建 MysqlStorage 使用的锁表
+func InitMysqlLockTable(db *gorm.DB) error {
+	return db.AutoMigrate(&mysqlLockRecord{})
+}
+
+// MysqlStorage 是基于 MySQL 实现的 LockStorage，锁表需要先通过 InitMysqlLockTable 创建
+// 过期时间使用数据库时间计算，避免各实例之间的时钟偏差
+type MysqlStorage struct {
+	config Config
+	db     *gorm.DB
+	owner  string
+	token  int64 // 最近一次获取锁得到的 fencing token
+}
+
+// NewMysqlStorage 创建一个新的 MysqlStorage 实例，db 可以使用 dbmysql.InitMysql 创建，配置从库时锁操作都使用主库
+func NewMysqlStorage(db *gorm.DB, config Config) *MysqlStorage {
+	owner := config.Owner
+	if owner == "" {
+		owner = GenerateOwner()
+	}
+	return &MysqlStorage{
+		config: config,
+		db:     db,
+		owner:  owner,
+	}
+}
+
+// Owner 返回锁的持有者标识
+func (m *MysqlStorage) Owner() string {
+	return m.owner
+}
+
+// Lock 获取锁，锁被其他持有者持有时随机间隔重试
+func (m *MysqlStorage) Lock(ctx context.Context) (bool, error) {
+	return retryLock(ctx, m.TryLock)
+}
+
+// TryLock 只尝试获取一次锁，不重试
+func (m *MysqlStorage) TryLock(ctx context.Context) (bool, error) {
+	db := m.db.WithContext(ctx)
+	// 确保锁记录存在，并发插入时忽略冲突
+	insertErr := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&mysqlLockRecord{
+		LockKey:  m.config.Key,
+		ExpireAt: time.Unix(0, 0),
+	}).Error
+	if insertErr != nil {
+		return false, insertErr
+	}
+
+	// 锁未被持有或已过期时获取锁，可重入模式下当前持有者可以重复获取
+	// MySQL 按从左到右的顺序计算赋值，owner 必须在 count 和 token 之后更新
+	condition := "owner = '' OR expire_at <= NOW(3)"
+	if m.config.Reentrant {
+		condition += " OR owner = @owner"
+	}
+	res := db.Exec("UPDATE "+mysqlLockTable+" SET "+
+		"count = IF(owner = @owner AND expire_at > NOW(3), count + 1, 1), "+
+		"token = IF(owner = @owner AND expire_at > NOW(3), token, token + 1), "+
+		"owner = @owner, expire_at = DATE_ADD(NOW(3), INTERVAL @ttl MICROSECOND) "+
+		"WHERE lock_key = @key AND ("+condition+")", m.namedArgs())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+
+	// 配置了读写分离时从主库读取，从库的复制延迟可能读不到刚写入的记录
+	var record mysqlLockRecord
+	if err := db.Clauses(dbresolver.Write).Where("lock_key = ? AND owner = ?", m.config.Key, m.owner).Take(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 获取后立即过期并被其他持有者获取
+			return false, nil
+		}
+		return false, err
+	}
+	if m.config.Fencing {
+		m.token = record.Token
+	}
+	return true, nil
+}
+
+// Unlock 释放锁，可重入模式下重入次数归零时才真正释放
+func (m *MysqlStorage) Unlock(ctx context.Context) (bool, error) {
+	// MySQL 按从左到右的顺序计算赋值，expire_at 和 owner 使用递减后的 count
+	res := m.db.WithContext(ctx).Exec("UPDATE "+mysqlLockTable+" SET "+
+		"count = count - 1, "+
+		"expire_at = IF(count > 0, DATE_ADD(NOW(3), INTERVAL @ttl MICROSECOND), NOW(3)), "+
+		"owner = IF(count > 0, owner, '') "+
+		"WHERE lock_key = @key AND owner = @owner AND expire_at > NOW(3)", m.namedArgs())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, ErrNotOwner
+	}
+	return true, nil
+}
+
+// Renewal 锁续期
+func (m *MysqlStorage) Renewal(ctx context.Context) (bool, error) {
+	res := m.db.WithContext(ctx).Exec("UPDATE "+mysqlLockTable+" SET "+
+		"expire_at = DATE_ADD(NOW(3), INTERVAL @ttl MICROSECOND) "+
+		"WHERE lock_key = @key AND owner = @owner AND expire_at > NOW(3)", m.namedArgs())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// FencingToken 返回最近一次获取锁得到的 fencing token，未开启 fencing 时返回0
+func (m *MysqlStorage) FencingToken() int64 {
+	return m.token
+}
+
+// namedArgs 返回 SQL 语句使用的命名参数，过期时间以数据库当前时间计算
+func (m *MysqlStorage) namedArgs() map[string]any {
+	return map[string]any{
+		"key":   m.config.Key,
+		"owner": m.owner,
+		"ttl":   m.config.TTL.Microseconds(),
+	}
+}
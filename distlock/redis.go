@@ -2,7 +2,11 @@ package distlock
 
 import (
 	"context"
+	"errors"
+	"math/rand"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-redsync/redsync/v4"
 	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
@@ -13,41 +17,177 @@ import (
 type RedisStorage struct {
 	config Config
 	mu     sync.Mutex
+	client goredislib.UniversalClient
 	rs     *redsync.Redsync // 这里持有一个 Redsync 实例
 	mutex  *redsync.Mutex   // 互斥锁
+	owner  string           // 可重入或 fencing 模式下的持有者标识
+	token  int64            // fencing 模式下本次获取锁得到的 fencing token
 }
 
+const (
+	lockTries         = 32
+	lockMinRetryDelay = 50 * time.Millisecond
+	lockMaxRetryDelay = 250 * time.Millisecond
+)
+
 // NewRedisStorage 创建一个新的 RedisStorage 实例
 func NewRedisStorage(client goredislib.UniversalClient, config Config) *RedisStorage {
-	rs := redsync.New(goredis.NewPool(client))
+	return newRedisStorage(client, redsync.New(goredis.NewPool(client)), config)
+}
+
+// newRedisStorage 使用已有的 Redsync 实例创建 RedisStorage，供 Locker 复用
+func newRedisStorage(client goredislib.UniversalClient, rs *redsync.Redsync, config Config) *RedisStorage {
 	mutex := rs.NewMutex(config.Key, redsync.WithExpiry(config.TTL))
+	owner := config.Owner
+	if (config.Reentrant || config.Fencing) && owner == "" {
+		owner = GenerateOwner()
+	}
 	return &RedisStorage{
 		config: config,
+		client: client,
 		rs:     rs,
 		mutex:  mutex,
+		owner:  owner,
 	}
 }
 
+// Owner 返回可重入或 fencing 模式下的持有者标识，可传给其他锁实例的 Config.Owner 实现跨实例重入
+func (r *RedisStorage) Owner() string {
+	return r.owner
+}
+
 // Lock 获取锁
 func (r *RedisStorage) Lock(ctx context.Context) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if err := r.mutex.LockContext(ctx); err != nil {
-		return false, err
+	if r.config.Reentrant {
+		return retryLock(ctx, r.reentrantLock)
+	}
+	if r.config.Fencing {
+		return retryLock(ctx, r.fencingLock)
+	}
+	return lockResult(ctx, r.mutex.LockContext(ctx))
+}
+
+// TryLock 只尝试获取一次锁，不重试
+func (r *RedisStorage) TryLock(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.config.Reentrant {
+		return r.reentrantLock(ctx)
 	}
-	return true, nil
+	if r.config.Fencing {
+		return r.fencingLock(ctx)
+	}
+	return lockResult(ctx, r.mutex.TryLockContext(ctx))
 }
 
 // Unlock 释放锁
 func (r *RedisStorage) Unlock(ctx context.Context) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.mutex.UnlockContext(ctx)
+	if r.config.Reentrant {
+		return r.reentrantUnlock(ctx)
+	}
+	if r.config.Fencing {
+		return r.fencingUnlock(ctx)
+	}
+	ok, err := r.mutex.UnlockContext(ctx)
+	if !ok && !isRedisError(err) {
+		// 锁已过期或被其他持有者获取
+		return false, ErrNotOwner
+	}
+	return ok, err
 }
 
 // Renewal 锁续期
 func (r *RedisStorage) Renewal(ctx context.Context) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	return r.mutex.ExtendContext(ctx)
+	if r.config.Reentrant {
+		return r.reentrantRenewal(ctx)
+	}
+	if r.config.Fencing {
+		return r.fencingRenewal(ctx)
+	}
+	ok, err := r.mutex.ExtendContext(ctx)
+	if !ok && err != nil && !isRedisError(err) && ctx.Err() == nil {
+		// 锁已过期或被其他持有者获取
+		return false, nil
+	}
+	return ok, err
+}
+
+// FencingToken 返回最近一次获取锁得到的 fencing token，未开启 fencing 时返回0
+func (r *RedisStorage) FencingToken() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// retryLock 锁被其他持有者持有时，与 redsync 一致地随机间隔重试
+func retryLock(ctx context.Context, attempt func(ctx context.Context) (bool, error)) (bool, error) {
+	for i := 0; i < lockTries; i++ {
+		if i > 0 {
+			delay := lockMinRetryDelay + time.Duration(rand.Int63n(int64(lockMaxRetryDelay-lockMinRetryDelay)))
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return false, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		ok, err := attempt(ctx)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fencingKey 返回 fencing token 计数器的 key，与锁的 key 位于同一个集群 slot
+func (r *RedisStorage) fencingKey() string {
+	return sameSlotKey(r.config.Key, "fencing")
+}
+
+// sameSlotKey 返回与 key 位于同一个集群 slot 的关联 key
+func sameSlotKey(key, suffix string) string {
+	if start := strings.Index(key, "{"); start >= 0 {
+		if end := strings.Index(key[start+1:], "}"); end > 0 {
+			// 已经包含 hash tag，直接追加后缀
+			return key + ":" + suffix
+		}
+	}
+	return "{" + key + "}:" + suffix
+}
+
+// lockResult 将 redsync 获取锁的错误转换为 Lock 接口的语义，锁被其他持有者持有时返回 false 和 nil
+func lockResult(ctx context.Context, err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	// redsync 在 ctx 取消时同样返回 ErrFailed
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, ctxErr
+	}
+	if isRedisError(err) {
+		return false, err
+	}
+	var takenErr *redsync.ErrTaken
+	var nodeTakenErr *redsync.ErrNodeTaken
+	if errors.Is(err, redsync.ErrFailed) || errors.As(err, &takenErr) || errors.As(err, &nodeTakenErr) {
+		return false, nil
+	}
+	return false, err
+}
+
+// isRedisError 判断是否为与 Redis 通信失败的错误
+func isRedisError(err error) bool {
+	var redisErr *redsync.RedisError
+	return errors.As(err, &redisErr)
 }
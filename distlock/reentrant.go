@@ -0,0 +1,92 @@
+package distlock
+
+import (
+	"context"
+
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// 可重入锁使用 hash 存储，field 为持有者标识，value 为重入次数，开启 fencing 时额外记录本次持有的 fencing token
+
+// reentrantTokenField 可重入锁 hash 中记录 fencing token 的 field
+const reentrantTokenField = "__fencing_token"
+
+// reentrantLockScript 锁不存在或由当前持有者持有时累加重入次数，返回{重入次数, fencing token}，被其他持有者持有时重入次数为0
+var reentrantLockScript = goredislib.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	local count = redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	local token = 0
+	if ARGV[3] == "1" then
+		if count == 1 then
+			token = redis.call("INCR", KEYS[2])
+			redis.call("HSET", KEYS[1], ARGV[4], token)
+		else
+			token = tonumber(redis.call("HGET", KEYS[1], ARGV[4]))
+		end
+	end
+	return {count, token}
+end
+return {0, 0}
+`)
+
+// reentrantUnlockScript 减少重入次数，归零时删除锁，返回剩余重入次数，不是持有者时返回-1
+var reentrantUnlockScript = goredislib.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local count = redis.call("HINCRBY", KEYS[1], ARGV[1], -1)
+if count > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return count
+end
+redis.call("DEL", KEYS[1])
+return 0
+`)
+
+// reentrantRenewalScript 当前持有者持有锁时重置过期时间
+var reentrantRenewalScript = goredislib.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// reentrantLock 尝试获取一次可重入锁
+func (r *RedisStorage) reentrantLock(ctx context.Context) (bool, error) {
+	fencing := "0"
+	if r.config.Fencing {
+		fencing = "1"
+	}
+	res, err := reentrantLockScript.Run(ctx, r.client, []string{r.config.Key, r.fencingKey()},
+		r.owner, r.config.TTL.Milliseconds(), fencing, reentrantTokenField).Int64Slice()
+	if err != nil {
+		return false, err
+	}
+	if res[0] == 0 {
+		return false, nil
+	}
+	r.token = res[1]
+	return true, nil
+}
+
+// reentrantUnlock 释放一次可重入锁
+func (r *RedisStorage) reentrantUnlock(ctx context.Context) (bool, error) {
+	count, err := reentrantUnlockScript.Run(ctx, r.client, []string{r.config.Key}, r.owner, r.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	if count < 0 {
+		return false, ErrNotOwner
+	}
+	return true, nil
+}
+
+// reentrantRenewal 续期可重入锁
+func (r *RedisStorage) reentrantRenewal(ctx context.Context) (bool, error) {
+	res, err := reentrantRenewalScript.Run(ctx, r.client, []string{r.config.Key}, r.owner, r.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
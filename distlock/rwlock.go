@@ -0,0 +1,224 @@
+package distlock
+
+import (
+	"context"
+
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// 读写锁使用三个位于同一个集群 slot 的 key：
+// 写锁为 string，value 为持有者标识；读锁为与信号量相同的租约 zset，member 为持有者标识，score 为过期时间（毫秒）；
+// 等待中的写者为 string，存在时新的读者无法获取锁，避免读者持续持有导致写者饥饿
+
+// rwReadLockScript 没有写者持有或等待时获取读锁，同一持有者重复获取时返回0
+var rwReadLockScript = goredislib.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", KEYS[3]) == 1 then
+	return 0
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+if redis.call("ZSCORE", KEYS[2], ARGV[1]) then
+	return 0
+end
+redis.call("ZADD", KEYS[2], now + tonumber(ARGV[2]), ARGV[1])
+if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// rwWriteLockScript 没有写者和读者持有时获取写锁，其他写者在等待时让其优先获取；
+// ARGV[3] 为 "1" 时获取失败会登记为等待中的写者，阻止新的读者获取锁
+var rwWriteLockScript = goredislib.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+local waiting = redis.call("GET", KEYS[3])
+if waiting and waiting ~= ARGV[1] then
+	return 0
+end
+if redis.call("EXISTS", KEYS[1]) == 0 and redis.call("ZCARD", KEYS[2]) == 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	if waiting then
+		redis.call("DEL", KEYS[3])
+	end
+	return 1
+end
+if ARGV[3] == "1" then
+	redis.call("SET", KEYS[3], ARGV[1], "PX", ARGV[2])
+end
+return 0
+`)
+
+// rwOwnerDelScript 当前持有者持有 key 时删除，用于释放写锁和撤销写者的等待登记
+var rwOwnerDelScript = goredislib.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// rwWriteRenewalScript 当前持有者持有写锁时重置过期时间
+var rwWriteRenewalScript = goredislib.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RWLock 基于 Redis 的分布式读写锁，多个读者可以同时持有读锁，写者独占
+// 写者阻塞等待时会阻止新的读者获取锁，避免写者饥饿；TTL 和自动续期的语义与 DistLock 一致
+// 读锁和写锁均不可重入，也不支持从读锁升级为写锁，每个持有者应使用独立的 RWLock 实例
+type RWLock struct {
+	readLock  *DistLock
+	writeLock *DistLock
+}
+
+// NewRWLock 创建新的读写锁实例，options 同时作用于读锁和写锁
+func NewRWLock(client goredislib.UniversalClient, config Config, options ...Option) *RWLock {
+	owner := config.Owner
+	if owner == "" {
+		owner = GenerateOwner()
+	}
+	base := rwStorage{
+		client:     client,
+		config:     config,
+		owner:      owner,
+		writeKey:   sameSlotKey(config.Key, "write"),
+		readKey:    sameSlotKey(config.Key, "read"),
+		waitingKey: sameSlotKey(config.Key, "write_waiting"),
+	}
+	readStore, writeStore := base, base
+	writeStore.write = true
+	return &RWLock{
+		readLock:  NewDistLock(&readStore, &config, options...),
+		writeLock: NewDistLock(&writeStore, &config, options...),
+	}
+}
+
+// RLock 获取读锁，有写者持有或等待时返回 ErrLockHeld
+func (rw *RWLock) RLock(ctx context.Context) (bool, error) {
+	return rw.readLock.Lock(ctx)
+}
+
+// TryRLock 只尝试获取一次读锁，不等待
+func (rw *RWLock) TryRLock(ctx context.Context) (bool, error) {
+	return rw.readLock.TryLock(ctx)
+}
+
+// RUnlock 释放读锁
+func (rw *RWLock) RUnlock(ctx context.Context) (bool, error) {
+	return rw.readLock.Unlock(ctx)
+}
+
+// Lock 获取写锁，等待期间阻止新的读者获取锁，超过重试次数仍未获取到时返回 ErrLockHeld
+func (rw *RWLock) Lock(ctx context.Context) (bool, error) {
+	return rw.writeLock.Lock(ctx)
+}
+
+// TryLock 只尝试获取一次写锁，不等待，也不会阻止新的读者
+func (rw *RWLock) TryLock(ctx context.Context) (bool, error) {
+	return rw.writeLock.TryLock(ctx)
+}
+
+// Unlock 释放写锁
+func (rw *RWLock) Unlock(ctx context.Context) (bool, error) {
+	return rw.writeLock.Unlock(ctx)
+}
+
+// ReadLock 返回读锁，可用于 LockWithWait、Lost、Context 等操作
+func (rw *RWLock) ReadLock() *DistLock {
+	return rw.readLock
+}
+
+// WriteLock 返回写锁，可用于 LockWithWait、Lost、Context 等操作
+func (rw *RWLock) WriteLock() *DistLock {
+	return rw.writeLock
+}
+
+// rwStorage 读写锁的存储，write 为 true 时操作写锁，否则操作读锁
+type rwStorage struct {
+	client     goredislib.UniversalClient
+	config     Config
+	owner      string
+	write      bool
+	writeKey   string
+	readKey    string
+	waitingKey string
+}
+
+// Lock 获取锁，写者等待期间会登记为等待中的写者，放弃等待时撤销登记
+func (s *rwStorage) Lock(ctx context.Context) (bool, error) {
+	if !s.write {
+		return retryLock(ctx, s.TryLock)
+	}
+	ok, err := retryLock(ctx, func(ctx context.Context) (bool, error) {
+		return s.writeLock(ctx, true)
+	})
+	if !ok {
+		// 使用新的上下文撤销登记，避免 ctx 已取消时登记残留到过期
+		if _, delErr := rwOwnerDelScript.Run(context.WithoutCancel(ctx), s.client, []string{s.waitingKey}, s.owner).Result(); delErr != nil && err == nil {
+			err = delErr
+		}
+	}
+	return ok, err
+}
+
+// TryLock 只尝试获取一次锁
+func (s *rwStorage) TryLock(ctx context.Context) (bool, error) {
+	if s.write {
+		return s.writeLock(ctx, false)
+	}
+	res, err := rwReadLockScript.Run(ctx, s.client, []string{s.writeKey, s.readKey, s.waitingKey},
+		s.owner, s.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Unlock 释放锁
+func (s *rwStorage) Unlock(ctx context.Context) (bool, error) {
+	var res int64
+	var err error
+	if s.write {
+		res, err = rwOwnerDelScript.Run(ctx, s.client, []string{s.writeKey}, s.owner).Int64()
+	} else {
+		res, err = leaseReleaseScript.Run(ctx, s.client, []string{s.readKey}, s.owner).Int64()
+	}
+	if err != nil {
+		return false, err
+	}
+	if res == 0 {
+		return false, ErrNotOwner
+	}
+	return true, nil
+}
+
+// Renewal 锁续期
+func (s *rwStorage) Renewal(ctx context.Context) (bool, error) {
+	script, key := leaseRenewalScript, s.readKey
+	if s.write {
+		script, key = rwWriteRenewalScript, s.writeKey
+	}
+	res, err := script.Run(ctx, s.client, []string{key}, s.owner, s.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// writeLock 尝试获取一次写锁，wait 为 true 时获取失败会登记为等待中的写者
+func (s *rwStorage) writeLock(ctx context.Context, wait bool) (bool, error) {
+	waitArg := "0"
+	if wait {
+		waitArg = "1"
+	}
+	res, err := rwWriteLockScript.Run(ctx, s.client, []string{s.writeKey, s.readKey, s.waitingKey},
+		s.owner, s.config.TTL.Milliseconds(), waitArg).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
@@ -0,0 +1,165 @@
+package distlock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredislib "github.com/redis/go-redis/v9"
+)
+
+// 信号量使用 zset 记录持有者的租约，member 为持有者标识，score 为租约过期时间（毫秒）
+// 进程异常退出后租约到期自动失效，获取许可时会先清理过期的租约
+
+// semaphoreAcquireScript 持有者数量小于 ARGV[3] 时获取许可，同一持有者重复获取时返回0
+var semaphoreAcquireScript = goredislib.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	return 0
+end
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// semaphoreHoldersScript 清理过期的租约并返回持有者数量
+var semaphoreHoldersScript = goredislib.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+return redis.call("ZCARD", KEYS[1])
+`)
+
+// leaseReleaseScript 删除持有者的租约，不是持有者时返回0
+var leaseReleaseScript = goredislib.NewScript(`
+return redis.call("ZREM", KEYS[1], ARGV[1])
+`)
+
+// leaseRenewalScript 持有者的租约未过期时重置过期时间
+var leaseRenewalScript = goredislib.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score or tonumber(score) <= now then
+	return 0
+end
+redis.call("ZADD", KEYS[1], "XX", now + tonumber(ARGV[2]), ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// Semaphore 基于 Redis 的分布式信号量，所有实例中最多 permits 个持有者同时持有许可
+// 每个持有者的租约 TTL 和自动续期的语义与 DistLock 一致，每个持有者应使用独立的 Semaphore 实例，不可重入
+type Semaphore struct {
+	lock  *DistLock
+	store *semaphoreStorage
+}
+
+// NewSemaphore 创建新的信号量实例，相同 Key 的信号量需要使用相同的 permits
+func NewSemaphore(client goredislib.UniversalClient, config Config, permits int64, options ...Option) *Semaphore {
+	owner := config.Owner
+	if owner == "" {
+		owner = GenerateOwner()
+	}
+	store := &semaphoreStorage{
+		client:  client,
+		config:  config,
+		owner:   owner,
+		permits: permits,
+	}
+	return &Semaphore{
+		lock:  NewDistLock(store, &config, options...),
+		store: store,
+	}
+}
+
+// Acquire 获取许可，许可已用完时随机间隔重试，超过重试次数仍未获取到时返回 ErrLockHeld
+func (s *Semaphore) Acquire(ctx context.Context) (bool, error) {
+	return s.lock.Lock(ctx)
+}
+
+// TryAcquire 只尝试获取一次许可，许可已用完时返回 ErrLockHeld
+func (s *Semaphore) TryAcquire(ctx context.Context) (bool, error) {
+	return s.lock.TryLock(ctx)
+}
+
+// AcquireWithWait 按重试间隔策略反复尝试获取许可，最多等待 maxWait，超时返回 ErrLockTimeout
+func (s *Semaphore) AcquireWithWait(ctx context.Context, maxWait time.Duration) (bool, error) {
+	return s.lock.LockWithWait(ctx, maxWait)
+}
+
+// Release 释放许可，租约已过期时返回 ErrNotOwner
+func (s *Semaphore) Release(ctx context.Context) (bool, error) {
+	return s.lock.Unlock(ctx)
+}
+
+// Lost 返回本次持有期间租约失效时关闭的 channel
+func (s *Semaphore) Lost() <-chan struct{} {
+	return s.lock.Lost()
+}
+
+// Context 返回租约失效时被取消的上下文，取消原因为 ErrLockLost
+func (s *Semaphore) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	return s.lock.Context(ctx)
+}
+
+// Holders 返回当前持有许可的数量
+func (s *Semaphore) Holders(ctx context.Context) (int64, error) {
+	return semaphoreHoldersScript.Run(ctx, s.store.client, []string{s.store.config.Key}).Int64()
+}
+
+// semaphoreStorage 信号量的存储，每个实例对应一个持有者
+type semaphoreStorage struct {
+	client  goredislib.UniversalClient
+	config  Config
+	owner   string
+	permits int64
+}
+
+// Lock 获取许可，许可已用完时随机间隔重试
+func (s *semaphoreStorage) Lock(ctx context.Context) (bool, error) {
+	return retryLock(ctx, s.TryLock)
+}
+
+// TryLock 只尝试获取一次许可
+func (s *semaphoreStorage) TryLock(ctx context.Context) (bool, error) {
+	if s.permits <= 0 {
+		return false, fmt.Errorf("semaphore permits must be greater than 0")
+	}
+	res, err := semaphoreAcquireScript.Run(ctx, s.client, []string{s.config.Key},
+		s.owner, s.config.TTL.Milliseconds(), s.permits).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Unlock 释放许可
+func (s *semaphoreStorage) Unlock(ctx context.Context) (bool, error) {
+	res, err := leaseReleaseScript.Run(ctx, s.client, []string{s.config.Key}, s.owner).Int64()
+	if err != nil {
+		return false, err
+	}
+	if res == 0 {
+		return false, ErrNotOwner
+	}
+	return true, nil
+}
+
+// Renewal 租约续期
+func (s *semaphoreStorage) Renewal(ctx context.Context) (bool, error) {
+	res, err := leaseRenewalScript.Run(ctx, s.client, []string{s.config.Key}, s.owner, s.config.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
@@ -0,0 +1,243 @@
+package distlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morehao/golib/storages/dbmysql"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRedisStorageConformance(t *testing.T) {
+	rdbClient := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+	})
+	testStorageConformance(t, "redis", func(config Config) Lock {
+		return NewRedisStorage(rdbClient, config)
+	})
+}
+
+func TestMemoryStorageConformance(t *testing.T) {
+	testStorageConformance(t, "memory", func(config Config) Lock {
+		return NewMemoryStorage(config)
+	})
+}
+
+func TestMysqlStorageConformance(t *testing.T) {
+	cfg := &dbmysql.MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+	}
+	db, err := dbmysql.InitMysql(cfg)
+	if err != nil {
+		t.Skipf("mysql is not available: %v", err)
+	}
+	assert.Nil(t, InitMysqlLockTable(db))
+	testStorageConformance(t, "mysql", func(config Config) Lock {
+		return NewMysqlStorage(db, config)
+	})
+}
+
+func TestMysqlStorageWithReplicas(t *testing.T) {
+	cfg := &dbmysql.MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+		Replicas: []dbmysql.ReplicaConfig{{Addr: "127.0.0.1:3306"}},
+	}
+	db, err := dbmysql.InitMysql(cfg)
+	if err != nil {
+		t.Skipf("mysql is not available: %v", err)
+	}
+	defer dbmysql.Close(db)
+	assert.Nil(t, InitMysqlLockTable(db))
+	primary, err := db.DB()
+	assert.Nil(t, err)
+
+	// 记录锁表查询实际使用的连接池
+	var connPools []gorm.ConnPool
+	capture := func(tx *gorm.DB) {
+		if tx.Statement.Table == mysqlLockTable {
+			connPools = append(connPools, tx.Statement.ConnPool)
+		}
+	}
+	assert.Nil(t, db.Callback().Query().After("gorm:db_resolver").Register("test:capture_conn_pool", capture))
+
+	config := Config{Key: "test_mysql_replicas_" + GenerateOwner(), TTL: time.Second * 2, Fencing: true}
+	lock := NewDistLock(NewMysqlStorage(db, config), &config)
+	token, err := lock.LockWithToken(context.Background())
+	assert.Nil(t, err)
+	assert.Greater(t, token, int64(0))
+	_, err = lock.Unlock(context.Background())
+	assert.Nil(t, err)
+
+	assert.NotEmpty(t, connPools)
+	for _, connPool := range connPools {
+		assert.Same(t, primary, connPool)
+	}
+}
+
+// testStorageConformance 所有存储引擎共用的一致性测试
+func testStorageConformance(t *testing.T, name string, newStore func(config Config) Lock) {
+	ctx := context.Background()
+	newConfig := func(key string) Config {
+		return Config{
+			Key: "test_conformance_" + name + "_" + key + "_" + GenerateOwner(),
+			TTL: time.Second * 2,
+		}
+	}
+
+	t.Run("mutual exclusion", func(t *testing.T) {
+		config := newConfig("mutual_exclusion")
+		holder := NewDistLock(newStore(config), &config)
+		other := NewDistLock(newStore(config), &config)
+
+		ok, err := holder.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = other.TryLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		_, err = holder.Unlock(ctx)
+		assert.Nil(t, err)
+		ok, err = other.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = other.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("unlock by other owner", func(t *testing.T) {
+		config := newConfig("not_owner")
+		holderStore := newStore(config)
+		holder := NewDistLock(holderStore, &config)
+		ok, err := holder.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		_, err = newStore(config).Unlock(ctx)
+		assert.ErrorIs(t, err, ErrNotOwner)
+		renewed, err := newStore(config).Renewal(ctx)
+		assert.Nil(t, err)
+		assert.False(t, renewed)
+
+		_, err = holder.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("lock waits for release", func(t *testing.T) {
+		config := newConfig("wait")
+		holder := NewDistLock(newStore(config), &config)
+		waiter := NewDistLock(newStore(config), &config)
+		ok, err := holder.Lock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		time.AfterFunc(time.Millisecond*300, func() {
+			_, _ = holder.Unlock(ctx)
+		})
+		ok, err = waiter.Lock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = waiter.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		config := newConfig("expiry")
+		config.TTL = time.Millisecond * 500
+		holderStore := newStore(config)
+		holder := NewDistLock(holderStore, &config)
+		other := NewDistLock(newStore(config), &config)
+		ok, err := holder.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		<-holder.Lost()
+		time.Sleep(time.Millisecond * 100)
+		ok, err = other.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		// 锁过期后旧持有者无法续期和释放
+		renewed, err := holderStore.Renewal(ctx)
+		assert.Nil(t, err)
+		assert.False(t, renewed)
+		_, err = holder.Unlock(ctx)
+		assert.ErrorIs(t, err, ErrNotOwner)
+		_, err = other.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("auto renewal", func(t *testing.T) {
+		config := newConfig("auto_renewal")
+		config.TTL = time.Millisecond * 500
+		config.AutoRenewal = true
+		holder := NewDistLock(newStore(config), &config)
+		other := NewDistLock(newStore(config), &config)
+		ok, err := holder.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		time.Sleep(time.Millisecond * 1200)
+		_, err = other.TryLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+		select {
+		case <-holder.Lost():
+			t.Fatal("lock lost with auto renewal")
+		default:
+		}
+		_, err = holder.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("reentrant", func(t *testing.T) {
+		config := newConfig("reentrant")
+		config.Reentrant = true
+		holder := NewDistLock(newStore(config), &config)
+		other := NewDistLock(newStore(config), &config)
+
+		for i := 0; i < 2; i++ {
+			ok, err := holder.TryLock(ctx)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		}
+		_, err := holder.Unlock(ctx)
+		assert.Nil(t, err)
+		_, err = other.TryLock(ctx)
+		assert.ErrorIs(t, err, ErrLockHeld)
+
+		_, err = holder.Unlock(ctx)
+		assert.Nil(t, err)
+		ok, err := other.TryLock(ctx)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		_, err = other.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+
+	t.Run("fencing token", func(t *testing.T) {
+		config := newConfig("fencing")
+		config.Fencing = true
+		first := NewDistLock(newStore(config), &config)
+		second := NewDistLock(newStore(config), &config)
+
+		firstToken, err := first.LockWithToken(ctx)
+		assert.Nil(t, err)
+		assert.Greater(t, firstToken, int64(0))
+		_, err = first.Unlock(ctx)
+		assert.Nil(t, err)
+
+		secondToken, err := second.LockWithToken(ctx)
+		assert.Nil(t, err)
+		assert.Greater(t, secondToken, firstToken)
+		_, err = second.Unlock(ctx)
+		assert.Nil(t, err)
+	})
+}
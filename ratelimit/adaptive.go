@@ -0,0 +1,346 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morehao/golib/gcontext/gincontext"
+	"resty.dev/v3"
+)
+
+// ErrAdaptiveLimitExceeded 在途请求数达到自适应并发限制
+var ErrAdaptiveLimitExceeded = errors.New("adaptive concurrency limit exceeded")
+
+// AdaptiveAlgorithm 自适应并发限制的调整算法
+type AdaptiveAlgorithm string
+
+const (
+	// AdaptiveAIMD 加性增乘性减，请求成功且并发充分使用时上限加1，请求被丢弃或延迟超过阈值时按比例减小
+	AdaptiveAIMD AdaptiveAlgorithm = "aimd"
+	// AdaptiveGradient 梯度算法，按长期平均延迟与当前延迟的比值调整上限，延迟升高时上限随之下降
+	AdaptiveGradient AdaptiveAlgorithm = "gradient"
+)
+
+const (
+	gradientLongWindow = 600 // 梯度算法长期平均延迟的样本窗口
+)
+
+// AdaptiveOption 是一个函数类型，用于设置自适应并发限制的选项
+type AdaptiveOption func(cfg *adaptiveConfig)
+
+type adaptiveConfig struct {
+	algorithm        AdaptiveAlgorithm
+	initialLimit     int
+	minLimit         int
+	maxLimit         int
+	backoffRatio     float64            // 请求被丢弃时上限的缩小比例
+	latencyThreshold time.Duration      // AIMD 延迟阈值，超过时视为过载
+	tolerance        float64            // 梯度算法允许当前延迟相对长期平均延迟增长的倍数
+	smoothing        float64            // 梯度算法每次调整的平滑系数
+	onLimitChange    func(from, to int) // 上限变化时的回调
+}
+
+// WithAdaptiveAlgorithm 设置调整算法，默认为 AdaptiveAIMD
+func WithAdaptiveAlgorithm(algorithm AdaptiveAlgorithm) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.algorithm = algorithm
+	}
+}
+
+// WithLimitRange 设置初始并发上限和上限的调整范围
+func WithLimitRange(initialLimit, minLimit, maxLimit int) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.initialLimit = initialLimit
+		cfg.minLimit = minLimit
+		cfg.maxLimit = maxLimit
+	}
+}
+
+// WithBackoffRatio 设置过载时上限的缩小比例，取值范围 (0, 1)
+func WithBackoffRatio(ratio float64) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.backoffRatio = ratio
+	}
+}
+
+// WithLatencyThreshold 设置 AIMD 的延迟阈值，请求延迟超过阈值时视为过载，默认为1秒，为0时只按丢弃的请求判断过载
+func WithLatencyThreshold(threshold time.Duration) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.latencyThreshold = threshold
+	}
+}
+
+// WithTolerance 设置梯度算法允许的延迟增长倍数，必须不小于1
+func WithTolerance(tolerance float64) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.tolerance = tolerance
+	}
+}
+
+// WithOnLimitChange 设置并发上限变化时的回调
+func WithOnLimitChange(fn func(from, to int)) AdaptiveOption {
+	return func(cfg *adaptiveConfig) {
+		cfg.onLimitChange = fn
+	}
+}
+
+// AdaptiveLimiter 自适应并发限制器，根据请求延迟和丢弃情况动态调整允许的在途请求数，
+// 参考 Netflix concurrency-limits，在途请求数达到上限时直接拒绝，用于服务端过载保护和调用下游时的客户端限流
+type AdaptiveLimiter struct {
+	cfg      adaptiveConfig
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	longRtt  float64 // 梯度算法的长期平均延迟（纳秒），作为无负载延迟的估计
+}
+
+// NewAdaptiveLimiter 创建自适应并发限制器
+func NewAdaptiveLimiter(opts ...AdaptiveOption) (*AdaptiveLimiter, error) {
+	cfg := adaptiveConfig{
+		algorithm:        AdaptiveAIMD, // 默认使用 AIMD 算法
+		initialLimit:     20,           // 默认初始并发上限为20
+		minLimit:         1,            // 默认最小并发上限为1
+		maxLimit:         1000,         // 默认最大并发上限为1000
+		backoffRatio:     0.9,          // 默认过载时缩小为原来的90%
+		latencyThreshold: time.Second,  // 默认延迟超过1秒时视为过载
+		tolerance:        1.5,          // 默认允许延迟增长到1.5倍
+		smoothing:        0.2,          // 默认平滑系数为0.2
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.algorithm != AdaptiveAIMD && cfg.algorithm != AdaptiveGradient {
+		return nil, fmt.Errorf("unsupported adaptive algorithm: %s", cfg.algorithm)
+	}
+	if cfg.minLimit <= 0 || cfg.minLimit > cfg.maxLimit || cfg.initialLimit < cfg.minLimit || cfg.initialLimit > cfg.maxLimit {
+		return nil, errors.New("limit range must satisfy 0 < min <= initial <= max")
+	}
+	if cfg.backoffRatio <= 0 || cfg.backoffRatio >= 1 {
+		return nil, errors.New("backoff ratio must be between 0 and 1")
+	}
+	if cfg.tolerance < 1 {
+		return nil, errors.New("tolerance must be greater than or equal to 1")
+	}
+	return &AdaptiveLimiter{
+		cfg:   cfg,
+		limit: float64(cfg.initialLimit),
+	}, nil
+}
+
+// Limit 返回当前的并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight 返回当前的在途请求数
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+// Acquire 获取一个并发占用，在途请求数达到上限时返回 false，
+// 获取成功后必须调用 AdaptiveToken 的 Success、Dropped 或 Ignore 之一结束请求
+func (l *AdaptiveLimiter) Acquire() (*AdaptiveToken, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight >= int(l.limit) {
+		return nil, false
+	}
+	l.inflight++
+	return &AdaptiveToken{
+		limiter:  l,
+		start:    time.Now(),
+		inflight: l.inflight,
+	}, true
+}
+
+// Do 在并发限制内执行 fn，在途请求数达到上限时返回 ErrAdaptiveLimitExceeded，
+// fn 返回超时错误时视为过载，返回其他错误时不参与上限调整
+func (l *AdaptiveLimiter) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	token, ok := l.Acquire()
+	if !ok {
+		return ErrAdaptiveLimitExceeded
+	}
+	defer token.Ignore()
+
+	err := fn(ctx)
+	switch {
+	case err == nil:
+		token.Success()
+	case isTimeout(err):
+		token.Dropped()
+	}
+	return err
+}
+
+// Execute 在并发限制内执行 HTTP 请求，可用于 ghttp.Client 的 R、RWithResult 创建的请求，
+// 请求超时或下游返回 429、503、504 时视为下游过载
+func (l *AdaptiveLimiter) Execute(req *resty.Request, method, url string) (*resty.Response, error) {
+	token, ok := l.Acquire()
+	if !ok {
+		return nil, ErrAdaptiveLimitExceeded
+	}
+	defer token.Ignore()
+
+	resp, err := req.Execute(method, url)
+	switch {
+	case err != nil:
+		if isTimeout(err) {
+			token.Dropped()
+		}
+	case resp.StatusCode() == http.StatusTooManyRequests ||
+		resp.StatusCode() == http.StatusServiceUnavailable ||
+		resp.StatusCode() == http.StatusGatewayTimeout:
+		token.Dropped()
+	default:
+		token.Success()
+	}
+	return resp, err
+}
+
+// AdaptiveMiddleware 使用自适应并发限制保护服务，在途请求数达到上限时拒绝请求，
+// 响应 5xx 或处理超时时视为过载，其余请求按延迟调整上限
+func AdaptiveMiddleware(limiter *AdaptiveLimiter, opts ...MiddlewareOption) gin.HandlerFunc {
+	cfg := newMiddlewareConfig(opts)
+	return func(c *gin.Context) {
+		token, ok := limiter.Acquire()
+		if !ok {
+			gincontext.Abort(c, cfg.rejectErr)
+			return
+		}
+		// 处理过程中 panic 时不参与上限调整
+		defer token.Ignore()
+
+		c.Next()
+		ctxErr := c.Request.Context().Err()
+		switch {
+		case errors.Is(ctxErr, context.DeadlineExceeded) || c.Writer.Status() >= http.StatusInternalServerError || hasTimeoutError(c):
+			token.Dropped()
+		case ctxErr != nil:
+			// 客户端已断开，延迟不能反映服务的负载
+		default:
+			token.Success()
+		}
+	}
+}
+
+// hasTimeoutError 判断处理请求时是否记录了超时错误
+func hasTimeoutError(c *gin.Context) bool {
+	for _, err := range c.Errors {
+		if isTimeout(err.Err) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdaptiveToken 一次请求占用的并发数，结束请求的方法只有第一次调用生效
+type AdaptiveToken struct {
+	limiter  *AdaptiveLimiter
+	start    time.Time
+	inflight int // 获取占用时的在途请求数
+	once     sync.Once
+}
+
+// Success 请求成功，按请求延迟调整并发上限
+func (t *AdaptiveToken) Success() {
+	t.once.Do(func() {
+		t.limiter.release(t, adaptiveSuccess)
+	})
+}
+
+// Dropped 请求因过载被丢弃或超时，缩小并发上限
+func (t *AdaptiveToken) Dropped() {
+	t.once.Do(func() {
+		t.limiter.release(t, adaptiveDropped)
+	})
+}
+
+// Ignore 请求因与负载无关的原因失败，只释放占用，不调整并发上限
+func (t *AdaptiveToken) Ignore() {
+	t.once.Do(func() {
+		t.limiter.release(t, adaptiveIgnored)
+	})
+}
+
+type adaptiveOutcome int
+
+const (
+	adaptiveSuccess adaptiveOutcome = iota
+	adaptiveDropped
+	adaptiveIgnored
+)
+
+func (l *AdaptiveLimiter) release(token *AdaptiveToken, outcome adaptiveOutcome) {
+	rtt := time.Since(token.start)
+
+	l.mu.Lock()
+	l.inflight--
+	from := int(l.limit)
+	switch outcome {
+	case adaptiveSuccess:
+		if l.cfg.algorithm == AdaptiveGradient {
+			l.gradientSample(rtt, token.inflight)
+		} else {
+			l.aimdSample(rtt, token.inflight)
+		}
+	case adaptiveDropped:
+		l.limit *= l.cfg.backoffRatio
+	}
+	l.limit = min(max(l.limit, float64(l.cfg.minLimit)), float64(l.cfg.maxLimit))
+	to := int(l.limit)
+	l.mu.Unlock()
+
+	if from != to && l.cfg.onLimitChange != nil {
+		l.cfg.onLimitChange(from, to)
+	}
+}
+
+// aimdSample 延迟超过阈值时按比例减小上限，否则在并发充分使用时上限加1
+func (l *AdaptiveLimiter) aimdSample(rtt time.Duration, inflight int) {
+	if l.cfg.latencyThreshold > 0 && rtt > l.cfg.latencyThreshold {
+		l.limit *= l.cfg.backoffRatio
+		return
+	}
+	// 在途请求数远低于上限时增大上限没有意义，避免上限无限增长
+	if inflight*2 >= int(l.limit) {
+		l.limit++
+	}
+}
+
+// gradientSample 按长期平均延迟与本次延迟的比值调整上限，并保留 sqrt(limit) 的排队余量
+func (l *AdaptiveLimiter) gradientSample(rtt time.Duration, inflight int) {
+	sample := float64(max(rtt, time.Microsecond))
+	if l.longRtt == 0 {
+		l.longRtt = sample
+	} else {
+		l.longRtt += (sample - l.longRtt) / gradientLongWindow
+	}
+	if inflight*2 < int(l.limit) {
+		return
+	}
+
+	gradient := min(max(l.cfg.tolerance*l.longRtt/sample, 0.5), 1)
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	l.limit = l.limit*(1-l.cfg.smoothing) + newLimit*l.cfg.smoothing
+}
+
+// isTimeout 判断错误是否为超时
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
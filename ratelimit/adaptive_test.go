@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morehao/golib/protocol/ghttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("aimd", func(t *testing.T) {
+		var changes int32
+		limiter, err := NewAdaptiveLimiter(
+			WithLimitRange(2, 1, 4),
+			WithLatencyThreshold(time.Millisecond*20),
+			WithOnLimitChange(func(from, to int) {
+				atomic.AddInt32(&changes, 1)
+			}),
+		)
+		assert.Nil(t, err)
+
+		// 达到上限时拒绝
+		first, ok := limiter.Acquire()
+		assert.True(t, ok)
+		second, ok := limiter.Acquire()
+		assert.True(t, ok)
+		_, ok = limiter.Acquire()
+		assert.False(t, ok)
+		assert.Equal(t, 2, limiter.InFlight())
+
+		// 并发充分使用且成功时加性增长
+		first.Success()
+		second.Success()
+		second.Dropped()
+		assert.Equal(t, 4, limiter.Limit())
+		assert.Equal(t, 0, limiter.InFlight())
+
+		// 丢弃和延迟超过阈值时乘性减小
+		token, _ := limiter.Acquire()
+		token.Dropped()
+		assert.Equal(t, 3, limiter.Limit())
+		err = limiter.Do(ctx, func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 30)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, limiter.Limit())
+		assert.Equal(t, int32(3), atomic.LoadInt32(&changes))
+
+		// 超时视为过载，其他错误不调整上限
+		err = limiter.Do(ctx, func(ctx context.Context) error {
+			return context.DeadlineExceeded
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 2, limiter.Limit())
+		err = limiter.Do(ctx, func(ctx context.Context) error {
+			return assert.AnError
+		})
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 2, limiter.Limit())
+		assert.Equal(t, 0, limiter.InFlight())
+	})
+
+	t.Run("gradient", func(t *testing.T) {
+		limiter, err := NewAdaptiveLimiter(WithAdaptiveAlgorithm(AdaptiveGradient), WithLimitRange(10, 1, 100))
+		assert.Nil(t, err)
+
+		run := func(concurrency int, latency time.Duration) {
+			tokens := make([]*AdaptiveToken, 0, concurrency)
+			for i := 0; i < concurrency; i++ {
+				if token, ok := limiter.Acquire(); ok {
+					tokens = append(tokens, token)
+				}
+			}
+			time.Sleep(latency)
+			for _, token := range tokens {
+				token.Success()
+			}
+		}
+
+		// 延迟稳定时上限增长
+		for i := 0; i < 5; i++ {
+			run(limiter.Limit(), time.Millisecond*2)
+		}
+		grown := limiter.Limit()
+		assert.Greater(t, grown, 10)
+
+		// 延迟明显升高时上限下降
+		for i := 0; i < 5; i++ {
+			run(limiter.Limit(), time.Millisecond*20)
+		}
+		assert.Less(t, limiter.Limit(), grown)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewAdaptiveLimiter(WithLimitRange(10, 20, 30))
+		assert.NotNil(t, err)
+		_, err = NewAdaptiveLimiter(WithAdaptiveAlgorithm("unknown"))
+		assert.NotNil(t, err)
+		_, err = NewAdaptiveLimiter(WithBackoffRatio(1))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAdaptiveMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter, err := NewAdaptiveLimiter(WithLimitRange(1, 1, 1))
+	assert.Nil(t, err)
+
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(AdaptiveMiddleware(limiter))
+	router.GET("/slow", func(c *gin.Context) {
+		<-release
+		c.String(http.StatusOK, "done")
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- w
+	}()
+	assert.Eventually(t, func() bool { return limiter.InFlight() == 1 }, time.Second, time.Millisecond)
+
+	// 在途请求数达到上限时拒绝
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Contains(t, w.Body.String(), `"code":429`)
+
+	close(release)
+	assert.Equal(t, "done", (<-done).Body.String())
+	assert.Equal(t, 0, limiter.InFlight())
+}
+
+func TestAdaptiveMiddlewareOverload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(limiter *AdaptiveLimiter) *gin.Engine {
+		router := gin.New()
+		router.Use(AdaptiveMiddleware(limiter))
+		router.GET("/slow", func(c *gin.Context) {
+			time.Sleep(time.Millisecond * 30)
+			c.String(http.StatusOK, "done")
+		})
+		router.GET("/error", func(c *gin.Context) {
+			c.String(http.StatusInternalServerError, "error")
+		})
+		router.GET("/timeout", func(c *gin.Context) {
+			_ = c.Error(context.DeadlineExceeded)
+			c.String(http.StatusOK, "timeout")
+		})
+		router.GET("/canceled", func(c *gin.Context) {
+			c.String(http.StatusOK, "canceled")
+		})
+		return router
+	}
+	request := func(router *gin.Engine, path string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	t.Run("slow handlers", func(t *testing.T) {
+		limiter, err := NewAdaptiveLimiter(WithLimitRange(10, 1, 100), WithLatencyThreshold(time.Millisecond*20))
+		assert.Nil(t, err)
+		router := newRouter(limiter)
+		for i := 0; i < 5; i++ {
+			request(router, "/slow")
+		}
+		assert.Less(t, limiter.Limit(), 10)
+	})
+
+	t.Run("server errors and timeouts", func(t *testing.T) {
+		limiter, err := NewAdaptiveLimiter(WithLimitRange(10, 1, 100))
+		assert.Nil(t, err)
+		assert.Equal(t, time.Second, limiter.cfg.latencyThreshold)
+		router := newRouter(limiter)
+		request(router, "/error")
+		assert.Equal(t, 9, limiter.Limit())
+		request(router, "/timeout")
+		assert.Equal(t, 8, limiter.Limit())
+
+		// 客户端断开时不调整上限
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, "/canceled", nil).WithContext(ctx)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, 8, limiter.Limit())
+		assert.Equal(t, 0, limiter.InFlight())
+	})
+}
+
+func TestAdaptiveExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/overload" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := ghttp.NewClient(&ghttp.ClientConfig{Module: "adaptive", Host: server.URL})
+	limiter, err := NewAdaptiveLimiter(WithLimitRange(10, 1, 20))
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	resp, err := limiter.Execute(client.R(ctx), http.MethodGet, "/ok")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// 下游过载时缩小上限
+	resp, err = limiter.Execute(client.R(ctx), http.MethodGet, "/overload")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, 9, limiter.Limit())
+	assert.Equal(t, 0, limiter.InFlight())
+}
@@ -0,0 +1,184 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// Algorithm 限流算法
+type Algorithm string
+
+const (
+	// AlgorithmGCRA 令牌桶（GCRA），每个周期允许 Rate 个请求，最多突发 Burst 个请求
+	AlgorithmGCRA Algorithm = "gcra"
+	// AlgorithmSlidingWindowLog 滑动窗口日志，记录每个请求的时间，任意一个周期内最多 Rate 个请求
+	AlgorithmSlidingWindowLog Algorithm = "sliding_window_log"
+	// AlgorithmSlidingWindowCounter 滑动窗口计数，按上一个窗口的计数加权估算当前周期的请求数，内存占用固定
+	AlgorithmSlidingWindowCounter Algorithm = "sliding_window_counter"
+	// AlgorithmFixedWindow 固定窗口，从周期内的第一个请求开始计数，每个周期最多 Rate 个请求
+	AlgorithmFixedWindow Algorithm = "fixed_window"
+	// AlgorithmConcurrency 并发限制，同时最多 Rate 个请求，通过 Acquire 或 AllowN 获取占用，请求结束后需要调用 Lease.Release 释放，
+	// Period 为单个请求的最长占用时间，进程异常退出时未释放的占用在 Period 后失效
+	AlgorithmConcurrency Algorithm = "concurrency"
+)
+
+// Acquirer 使用 AlgorithmConcurrency 的限流器实现了 Acquirer
+type Acquirer interface {
+	Acquire(ctx context.Context, key string) (*Lease, error)     // 获取一个并发占用，被限流时返回 nil
+	WaitAcquire(ctx context.Context, key string) (*Lease, error) // 阻塞直到获取一个并发占用或 ctx 结束
+}
+
+// Lease AlgorithmConcurrency 获取成功时返回的占用，只能释放本次请求占用的并发数
+// 释放总是发往授予占用的存储，即使限流器此后在 Redis 和降级模式之间切换
+type Lease struct {
+	release func(ctx context.Context) error
+}
+
+// Release 释放占用，重复释放或 Lease 为 nil 时不做任何操作
+func (l *Lease) Release(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.release(ctx)
+}
+
+// algorithm 限流算法在具体存储上的实现，返回的 Result 不包含 Limit
+type algorithm interface {
+	allowN(ctx context.Context, key string, n int) (Result, error)
+	close() // 停止后台协程
+}
+
+// newRedisAlgorithm 创建基于 Redis 的限流算法实现
+func newRedisAlgorithm(cfg *Config) (algorithm, error) {
+	switch cfg.Algorithm {
+	case AlgorithmGCRA:
+		return &redisGCRA{
+			limiter: redis_rate.NewLimiter(cfg.RedisClient),
+			limit: redis_rate.Limit{
+				Rate:   cfg.Rate,
+				Period: cfg.Period,
+				Burst:  cfg.Burst,
+			},
+		}, nil
+	case AlgorithmSlidingWindowLog:
+		return newRedisScriptAlgorithm(cfg, slidingWindowLogScript), nil
+	case AlgorithmSlidingWindowCounter:
+		return newRedisScriptAlgorithm(cfg, slidingWindowCounterScript), nil
+	case AlgorithmFixedWindow:
+		return newRedisScriptAlgorithm(cfg, fixedWindowScript), nil
+	case AlgorithmConcurrency:
+		return newRedisScriptAlgorithm(cfg, concurrencyAcquireScript), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit algorithm: %s", cfg.Algorithm)
+	}
+}
+
+// newLocalAlgorithm 创建基于进程内存的限流算法实现，用于 Redis 不可用时降级
+// 配置了 Replicas 时每个实例只使用 1/Replicas 的配额，使所有实例的总配额与 Redis 限流时一致
+func newLocalAlgorithm(cfg *Config) algorithm {
+	replicas := max(cfg.Replicas, 1)
+	rateLimit := ceilDiv(cfg.Rate, replicas)
+	switch cfg.Algorithm {
+	case AlgorithmSlidingWindowLog:
+		return newLocalSlidingWindowLog(rateLimit, cfg.Period, cfg.CleanupInterval)
+	case AlgorithmSlidingWindowCounter:
+		return newLocalSlidingWindowCounter(rateLimit, cfg.Period, cfg.CleanupInterval)
+	case AlgorithmFixedWindow:
+		return newLocalFixedWindow(rateLimit, cfg.Period, cfg.CleanupInterval)
+	case AlgorithmConcurrency:
+		return newLocalConcurrency(rateLimit, cfg.Period, cfg.CleanupInterval)
+	default:
+		limit := rate.Limit(float64(cfg.Rate) / float64(replicas) / cfg.Period.Seconds())
+		return newTimeRateLimiter(limit, ceilDiv(cfg.Burst, replicas), cfg.CleanupInterval)
+	}
+}
+
+// ceilDiv 向上取整的除法，结果至少为1
+func ceilDiv(a, b int) int {
+	return max((a+b-1)/b, 1)
+}
+
+// redisGCRA 基于 redis_rate 的令牌桶实现
+type redisGCRA struct {
+	limiter *redis_rate.Limiter
+	limit   redis_rate.Limit
+}
+
+func (a *redisGCRA) allowN(ctx context.Context, key string, n int) (Result, error) {
+	res, err := a.limiter.AllowN(ctx, key, a.limit, n)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{
+		Allowed:    res.Allowed > 0,
+		Remaining:  res.Remaining,
+		RetryAfter: res.RetryAfter,
+		ResetAfter: res.ResetAfter,
+	}
+	if result.Allowed {
+		// redis_rate 允许时 RetryAfter 为-1
+		result.RetryAfter = 0
+	} else if n > a.limit.Burst {
+		result.RetryAfter = -1
+	}
+	return result, nil
+}
+
+func (a *redisGCRA) close() {}
+
+// redisScriptAlgorithm 基于 Lua 脚本的限流算法实现
+type redisScriptAlgorithm struct {
+	client redis.UniversalClient
+	script *redis.Script
+	rate   int
+	period time.Duration
+}
+
+func newRedisScriptAlgorithm(cfg *Config, script *redis.Script) *redisScriptAlgorithm {
+	return &redisScriptAlgorithm{
+		client: cfg.RedisClient,
+		script: script,
+		rate:   cfg.Rate,
+		period: cfg.Period,
+	}
+}
+
+func (a *redisScriptAlgorithm) allowN(ctx context.Context, key string, n int) (Result, error) {
+	member := newMember()
+	res, err := a.script.Run(ctx, a.client, []string{key}, a.rate, a.period.Milliseconds(), member, n).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		ResetAfter: time.Duration(res[3]) * time.Millisecond,
+	}
+	if res[2] < 0 {
+		result.RetryAfter = -1
+	}
+	if result.Allowed && a.script == concurrencyAcquireScript {
+		result.Lease = a.newLease(key, member, n)
+	}
+	return result, nil
+}
+
+// newLease 创建并发限制的占用，释放时只删除本次请求写入的 member
+func (a *redisScriptAlgorithm) newLease(key, member string, n int) *Lease {
+	members := make([]any, 0, n)
+	for i := 1; i <= n; i++ {
+		members = append(members, member+":"+strconv.Itoa(i))
+	}
+	return &Lease{release: func(ctx context.Context) error {
+		return a.client.ZRem(ctx, key, members...).Err()
+	}}
+}
+
+func (a *redisScriptAlgorithm) close() {}
@@ -0,0 +1,270 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgorithm(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+		DB:   0,
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	windowAlgorithms := []Algorithm{AlgorithmSlidingWindowLog, AlgorithmSlidingWindowCounter, AlgorithmFixedWindow}
+	for _, algo := range windowAlgorithms {
+		cfg := &Config{
+			RedisClient:     client,
+			Rate:            3,
+			Period:          time.Millisecond * 500,
+			CleanupInterval: time.Minute,
+			Algorithm:       algo,
+		}
+		remote, err := newRedisAlgorithm(cfg)
+		assert.Nil(t, err)
+		implementations := map[string]algorithm{
+			"redis": remote,
+			"local": newLocalAlgorithm(cfg),
+		}
+		for name, impl := range implementations {
+			t.Run(string(algo)+"/"+name, func(t *testing.T) {
+				key := "test_ratelimit_" + uuid.NewString()
+				for i := 0; i < 3; i++ {
+					res, err := impl.allowN(ctx, key, 1)
+					assert.Nil(t, err)
+					assert.True(t, res.Allowed)
+					assert.Equal(t, 2-i, res.Remaining)
+				}
+				res, err := impl.allowN(ctx, key, 1)
+				assert.Nil(t, err)
+				assert.False(t, res.Allowed)
+				assert.Equal(t, 0, res.Remaining)
+				assert.Greater(t, res.RetryAfter, time.Duration(0))
+				assert.LessOrEqual(t, res.RetryAfter, cfg.Period*2)
+				assert.Greater(t, res.ResetAfter, time.Duration(0))
+
+				// 请求数超过限制时永远无法满足
+				res, err = impl.allowN(ctx, key, 4)
+				assert.Nil(t, err)
+				assert.False(t, res.Allowed)
+				assert.Equal(t, time.Duration(-1), res.RetryAfter)
+
+				// 滑动窗口计数需要等待上一个窗口完全滑出
+				time.Sleep(cfg.Period * 2)
+				res, err = impl.allowN(ctx, key, 2)
+				assert.Nil(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, 1, res.Remaining)
+			})
+		}
+	}
+
+	cfg := &Config{
+		RedisClient:     client,
+		Rate:            2,
+		Period:          time.Millisecond * 500,
+		CleanupInterval: time.Minute,
+		Algorithm:       AlgorithmConcurrency,
+	}
+	remote, err := newRedisAlgorithm(cfg)
+	assert.Nil(t, err)
+	implementations := map[string]algorithm{
+		"redis": remote,
+		"local": newLocalAlgorithm(cfg),
+	}
+	for name, impl := range implementations {
+		t.Run(string(AlgorithmConcurrency)+"/"+name, func(t *testing.T) {
+			key := "test_ratelimit_" + uuid.NewString()
+			res, err := impl.allowN(ctx, key, 2)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 0, res.Remaining)
+			assert.NotNil(t, res.Lease)
+			lease := res.Lease
+			res, err = impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.False(t, res.Allowed)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+			// 释放后可以再次获取
+			assert.Nil(t, lease.Release(ctx))
+			res, err = impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+
+			// 未释放的占用在 Period 后失效
+			time.Sleep(cfg.Period + time.Millisecond*100)
+			res, err = impl.allowN(ctx, key, 2)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+		})
+	}
+
+	for name, impl := range implementations {
+		t.Run(string(AlgorithmConcurrency)+"/"+name+"/overlapping", func(t *testing.T) {
+			key := "test_ratelimit_" + uuid.NewString()
+			first, err := impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.True(t, first.Allowed)
+			second, err := impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.True(t, second.Allowed)
+
+			// 第二个请求先结束，只释放自己的占用，第一个请求的占用仍然有效
+			assert.Nil(t, second.Lease.Release(ctx))
+			assert.Nil(t, second.Lease.Release(ctx))
+			res, err := impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+			res, err = impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.False(t, res.Allowed)
+
+			assert.Nil(t, first.Lease.Release(ctx))
+			res, err = impl.allowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+		})
+	}
+
+	_, err = NewLimiter(WithRedisClient(client), WithAlgorithm("unknown"))
+	assert.NotNil(t, err)
+}
+
+func TestLeaseAfterModeChange(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+		DB:   0,
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	limiter, err := NewLimiter(WithRedisClient(client), WithAlgorithm(AlgorithmConcurrency), WithRate(2))
+	assert.Nil(t, err)
+	defer limiter.Close()
+	key := "test_ratelimit_" + uuid.NewString()
+
+	_, err = limiter.Allow(ctx, key)
+	assert.NotNil(t, err)
+	lease, err := limiter.(Acquirer).Acquire(ctx, key)
+	assert.Nil(t, err)
+	assert.NotNil(t, lease)
+	res, err := limiter.AllowN(ctx, key, 1)
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, int64(2), client.ZCard(ctx, key).Val())
+
+	// 切换到降级模式后，Redis 授予的占用仍然释放到 Redis
+	atomic.StoreUint32(&limiter.(*redisLimiter).redisAlive, 0)
+	assert.Equal(t, ModeFallback, limiter.Mode())
+	assert.Nil(t, lease.Release(ctx))
+	assert.Nil(t, res.Lease.Release(ctx))
+	assert.Equal(t, int64(0), client.ZCard(ctx, key).Val())
+}
+
+func TestAllowNAndWait(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+		DB:   0,
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	for _, algo := range []Algorithm{AlgorithmGCRA, AlgorithmSlidingWindowLog, AlgorithmSlidingWindowCounter, AlgorithmFixedWindow} {
+		t.Run(string(algo), func(t *testing.T) {
+			limiter, err := NewLimiter(
+				WithRedisClient(client),
+				WithAlgorithm(algo),
+				WithRate(2),
+				WithBurst(2),
+				WithPeriod(time.Millisecond*500),
+			)
+			assert.Nil(t, err)
+			key := "test_ratelimit_wait_" + uuid.NewString()
+
+			res, err := limiter.AllowN(ctx, key, 2)
+			assert.Nil(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 2, res.Limit)
+			res, err = limiter.AllowN(ctx, key, 1)
+			assert.Nil(t, err)
+			assert.False(t, res.Allowed)
+			assert.Greater(t, res.RetryAfter, time.Duration(0))
+
+			_, err = limiter.AllowN(ctx, key, 0)
+			assert.NotNil(t, err)
+
+			// Wait 阻塞到允许请求
+			start := time.Now()
+			assert.Nil(t, limiter.Wait(ctx, key))
+			assert.Greater(t, time.Since(start), time.Millisecond*100)
+
+			// ctx 结束时停止等待
+			for {
+				if allowed, _ := limiter.Allow(ctx, key); !allowed {
+					break
+				}
+			}
+			timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+			defer cancel()
+			assert.ErrorIs(t, limiter.Wait(timeoutCtx, key), context.DeadlineExceeded)
+		})
+	}
+
+	t.Run(string(AlgorithmConcurrency), func(t *testing.T) {
+		limiter, err := NewLimiter(
+			WithRedisClient(client),
+			WithAlgorithm(AlgorithmConcurrency),
+			WithRate(2),
+			WithPeriod(time.Second*5),
+		)
+		assert.Nil(t, err)
+		defer limiter.Close()
+		key := "test_ratelimit_wait_" + uuid.NewString()
+
+		// Wait 无法返回占用
+		assert.NotNil(t, limiter.Wait(ctx, key))
+		res, err := limiter.AllowN(ctx, key, 2)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2, res.Limit)
+		assert.NotNil(t, res.Lease)
+
+		// WaitAcquire 阻塞到占用被释放
+		time.AfterFunc(time.Millisecond*100, func() {
+			_ = res.Lease.Release(ctx)
+		})
+		start := time.Now()
+		lease, err := limiter.(Acquirer).WaitAcquire(ctx, key)
+		assert.Nil(t, err)
+		assert.NotNil(t, lease)
+		assert.Greater(t, time.Since(start), time.Millisecond*50)
+		assert.Equal(t, int64(1), client.ZCard(ctx, key).Val())
+		assert.Nil(t, lease.Release(ctx))
+		assert.Equal(t, int64(0), client.ZCard(ctx, key).Val())
+
+		// ctx 结束时停止等待
+		res, err = limiter.AllowN(ctx, key, 2)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		defer res.Lease.Release(ctx)
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+		defer cancel()
+		_, err = limiter.(Acquirer).WaitAcquire(timeoutCtx, key)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		gcra, err := NewLimiter(WithRedisClient(client))
+		assert.Nil(t, err)
+		defer gcra.Close()
+		_, err = gcra.(Acquirer).WaitAcquire(ctx, key)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,206 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// compositeScript 在一次调用中对多个维度执行令牌桶（GCRA）限流，所有维度都允许时才扣减配额，
+// KEYS 为各维度的限流 key，ARGV[1] 为本次请求数，之后每个维度依次为 rate、period（微秒）、burst，
+// key 保存理论到达时间（微秒），返回 {第一个拒绝的维度序号（允许时为0）, 每个维度的 是否允许, 剩余请求数, 重试等待时间（毫秒）, 完全恢复时间（毫秒）}
+var compositeScript = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local rejected = 0
+local states = {}
+for i = 1, #KEYS do
+	local s = {rate = tonumber(ARGV[i * 3 - 1]), period = tonumber(ARGV[i * 3]), burst = tonumber(ARGV[i * 3 + 1])}
+	s.debt = math.max(tonumber(redis.call("GET", KEYS[i]) or "0") - now, 0)
+	s.available = math.floor((s.burst * s.period - s.debt * s.rate) / s.period)
+	if s.available < n and rejected == 0 then
+		rejected = i
+	end
+	states[i] = s
+end
+local reply = {rejected}
+for i, s in ipairs(states) do
+	if rejected == 0 then
+		local debt = s.debt + n * s.period / s.rate
+		redis.call("SET", KEYS[i], string.format("%.3f", now + debt), "PX", math.max(math.ceil(debt / 1000), 1))
+		table.insert(reply, 1)
+		table.insert(reply, s.available - n)
+		table.insert(reply, 0)
+		table.insert(reply, math.ceil(debt / 1000))
+	elseif s.available < n then
+		local retry = -1
+		if n <= s.burst then
+			retry = math.max(math.ceil((s.debt - (s.burst - n) * s.period / s.rate) / 1000), 1)
+		end
+		table.insert(reply, 0)
+		table.insert(reply, math.max(s.available, 0))
+		table.insert(reply, retry)
+		table.insert(reply, math.ceil(s.debt / 1000))
+	else
+		table.insert(reply, 1)
+		table.insert(reply, s.available)
+		table.insert(reply, 0)
+		table.insert(reply, math.ceil(s.debt / 1000))
+	end
+end
+return reply
+`)
+
+// Quota 复合限流中的一个维度，使用令牌桶（GCRA）算法
+type Quota struct {
+	Name   string        // 维度名称，如 user、tenant、api_key，同一个复合限流器内唯一
+	Rate   int           // 每个限流周期允许的最大请求数
+	Period time.Duration // 限流周期
+	Burst  int           // 令牌桶的最大容量，默认与 Rate 相同
+	// HashTag 使用该维度的限流标识作为所有维度 key 的 hash tag，最多只能有一个维度设置，
+	// 该维度必须是层级中最上层的维度，即同一个下层标识总是对应同一个该维度的标识，如用户总是属于同一个租户，
+	// 没有维度设置时所有 key 使用相同的 hash tag，只适用于单机和哨兵模式，集群模式下有多个维度时必须设置
+	HashTag bool
+}
+
+// defaultHashTag 没有维度设置 HashTag 时使用的 hash tag，集群模式下会使所有 key 集中在同一个 slot，因此只用于非集群客户端
+const defaultHashTag = "ratelimit"
+
+// CompositeResult 复合限流结果
+type CompositeResult struct {
+	Result                       // 汇总结果，被拒绝时为第一个拒绝的维度的结果，允许时为剩余请求数最少的维度的结果
+	RejectedBy string            // 第一个拒绝请求的维度名称，允许时为空
+	Quotas     map[string]Result // 每个维度的结果，请求被拒绝时其他维度的配额不会扣减
+}
+
+// CompositeLimiter 复合限流器，一次请求同时受多个维度的配额限制，
+// 所有维度在一次 Lua 脚本调用中原子判断，任一维度拒绝时所有维度都不扣减配额
+// 脚本同时操作多个 key，key 使用 hash tag 保证集群模式下位于同一个 slot
+type CompositeLimiter struct {
+	client  redis.UniversalClient
+	quotas  []Quota
+	hashTag string // 设置了 HashTag 的维度名称，为空时使用 defaultHashTag
+}
+
+// NewCompositeLimiter 创建复合限流器，quotas 的顺序即判断和上报拒绝维度的顺序
+func NewCompositeLimiter(client redis.UniversalClient, quotas ...Quota) (*CompositeLimiter, error) {
+	if client == nil {
+		return nil, errors.New("redis client is nil")
+	}
+	if len(quotas) == 0 {
+		return nil, errors.New("quotas is empty")
+	}
+	var hashTag string
+	names := make(map[string]struct{}, len(quotas))
+	normalized := make([]Quota, 0, len(quotas))
+	for _, quota := range quotas {
+		if quota.Name == "" {
+			return nil, errors.New("quota name is empty")
+		}
+		if _, ok := names[quota.Name]; ok {
+			return nil, fmt.Errorf("duplicate quota name: %s", quota.Name)
+		}
+		if quota.Rate <= 0 || quota.Period <= 0 {
+			return nil, fmt.Errorf("rate and period of quota %s must be greater than 0", quota.Name)
+		}
+		if quota.Burst <= 0 {
+			quota.Burst = quota.Rate
+		}
+		if quota.HashTag {
+			if hashTag != "" {
+				return nil, fmt.Errorf("hash tag is set on both quota %s and %s", hashTag, quota.Name)
+			}
+			hashTag = quota.Name
+		}
+		names[quota.Name] = struct{}{}
+		normalized = append(normalized, quota)
+	}
+	if _, ok := client.(*redis.ClusterClient); ok && hashTag == "" && len(normalized) > 1 {
+		return nil, errors.New("hash tag quota is required for redis cluster client")
+	}
+	return &CompositeLimiter{
+		client:  client,
+		quotas:  normalized,
+		hashTag: hashTag,
+	}, nil
+}
+
+// Allow 判断是否允许一个请求，keys 为维度名称到限流标识的映射，如 {"user": "42", "tenant": "acme"}
+func (l *CompositeLimiter) Allow(ctx context.Context, keys map[string]string) (CompositeResult, error) {
+	return l.AllowN(ctx, keys, 1)
+}
+
+// AllowN 判断是否允许 n 个请求，每个维度的 Redis key 为 {hash tag}:维度名称:限流标识，keys 必须包含所有维度
+func (l *CompositeLimiter) AllowN(ctx context.Context, keys map[string]string, n int) (CompositeResult, error) {
+	if n <= 0 {
+		return CompositeResult{}, errors.New("n must be greater than 0")
+	}
+	tag := defaultHashTag
+	if l.hashTag != "" {
+		tag = l.hashTag + ":" + keys[l.hashTag]
+	}
+	redisKeys := make([]string, 0, len(l.quotas))
+	args := make([]any, 0, len(l.quotas)*3+1)
+	args = append(args, n)
+	for _, quota := range l.quotas {
+		key, ok := keys[quota.Name]
+		if !ok || key == "" {
+			return CompositeResult{}, fmt.Errorf("key of quota %s is empty", quota.Name)
+		}
+		redisKeys = append(redisKeys, hashTagKey(tag, quota.Name+":"+key))
+		args = append(args, quota.Rate, quota.Period.Microseconds(), quota.Burst)
+	}
+
+	res, err := compositeScript.Run(ctx, l.client, redisKeys, args...).Int64Slice()
+	if err != nil {
+		return CompositeResult{}, err
+	}
+
+	result := CompositeResult{
+		Quotas: make(map[string]Result, len(l.quotas)),
+	}
+	summary := -1
+	for i, quota := range l.quotas {
+		values := res[1+i*4 : 5+i*4]
+		quotaRes := Result{
+			Allowed:    values[0] == 1,
+			Limit:      quota.Rate,
+			Remaining:  int(values[1]),
+			RetryAfter: time.Duration(values[2]) * time.Millisecond,
+			ResetAfter: time.Duration(values[3]) * time.Millisecond,
+		}
+		if values[2] < 0 {
+			quotaRes.RetryAfter = -1
+		}
+		result.Quotas[quota.Name] = quotaRes
+		if res[0] == 0 && (summary < 0 || quotaRes.Remaining < result.Remaining) {
+			summary = i
+			result.Result = quotaRes
+		}
+	}
+	if res[0] == 0 {
+		return result, nil
+	}
+
+	// 请求需要所有维度都允许，重试等待时间取所有拒绝维度中最长的
+	result.RejectedBy = l.quotas[res[0]-1].Name
+	result.Result = result.Quotas[result.RejectedBy]
+	for _, quotaRes := range result.Quotas {
+		if quotaRes.Allowed || result.RetryAfter < 0 {
+			continue
+		}
+		if quotaRes.RetryAfter < 0 || quotaRes.RetryAfter > result.RetryAfter {
+			result.RetryAfter = quotaRes.RetryAfter
+		}
+	}
+	return result, nil
+}
+
+// hashTagKey 为 key 添加 hash tag，集群模式下相同 tag 的 key 位于同一个 slot
+func hashTagKey(tag, key string) string {
+	return "{" + tag + "}:" + key
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompositeLimiter(t *testing.T) {
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs: []string{"127.0.0.1:6379"},
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	limiter, err := NewCompositeLimiter(client,
+		Quota{Name: "test_ratelimit_user", Rate: 2, Period: time.Millisecond * 500},
+		Quota{Name: "test_ratelimit_tenant", Rate: 3, Period: time.Minute},
+	)
+	assert.Nil(t, err)
+
+	tenant := uuid.NewString()
+	userA := map[string]string{"test_ratelimit_user": uuid.NewString(), "test_ratelimit_tenant": tenant}
+	userB := map[string]string{"test_ratelimit_user": uuid.NewString(), "test_ratelimit_tenant": tenant}
+
+	// 用户维度先用完配额
+	for i := 0; i < 2; i++ {
+		res, err := limiter.Allow(ctx, userA)
+		assert.Nil(t, err)
+		assert.True(t, res.Allowed)
+		assert.Empty(t, res.RejectedBy)
+	}
+	res, err := limiter.Allow(ctx, userA)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, "test_ratelimit_user", res.RejectedBy)
+	assert.Equal(t, 2, res.Limit)
+	assert.Greater(t, res.RetryAfter, time.Duration(0))
+	assert.LessOrEqual(t, res.RetryAfter, time.Millisecond*250)
+	assert.True(t, res.Quotas["test_ratelimit_tenant"].Allowed)
+	assert.Equal(t, 1, res.Quotas["test_ratelimit_tenant"].Remaining)
+
+	// 被拒绝的请求不扣减其他维度的配额，租户维度还剩1个
+	res, err = limiter.Allow(ctx, userB)
+	assert.Nil(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 3, res.Limit)
+	res, err = limiter.Allow(ctx, userB)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, "test_ratelimit_tenant", res.RejectedBy)
+	assert.Greater(t, res.RetryAfter, time.Second*10)
+
+	// 超过容量的请求永远无法满足
+	res, err = limiter.AllowN(ctx, map[string]string{"test_ratelimit_user": uuid.NewString(), "test_ratelimit_tenant": uuid.NewString()}, 3)
+	assert.Nil(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, "test_ratelimit_user", res.RejectedBy)
+	assert.Equal(t, time.Duration(-1), res.RetryAfter)
+
+	_, err = limiter.Allow(ctx, map[string]string{"test_ratelimit_user": "1"})
+	assert.NotNil(t, err)
+	_, err = NewCompositeLimiter(client, Quota{Name: "a", Rate: 1, Period: time.Second}, Quota{Name: "a", Rate: 1, Period: time.Second})
+	assert.NotNil(t, err)
+
+	// 设置了 HashTag 的维度作为所有 key 的 hash tag
+	tagged, err := NewCompositeLimiter(client,
+		Quota{Name: "test_ratelimit_user", Rate: 2, Period: time.Second},
+		Quota{Name: "test_ratelimit_tenant", Rate: 3, Period: time.Second, HashTag: true},
+	)
+	assert.Nil(t, err)
+	_, err = tagged.Allow(ctx, userA)
+	assert.Nil(t, err)
+	tag := "{test_ratelimit_tenant:" + tenant + "}:"
+	exists, err := client.Exists(ctx, tag+"test_ratelimit_user:"+userA["test_ratelimit_user"], tag+"test_ratelimit_tenant:"+tenant).Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), exists)
+	_, err = NewCompositeLimiter(client,
+		Quota{Name: "a", Rate: 1, Period: time.Second, HashTag: true},
+		Quota{Name: "b", Rate: 1, Period: time.Second, HashTag: true},
+	)
+	assert.NotNil(t, err)
+
+	// 集群模式下多个维度必须设置 HashTag，避免所有 key 集中在同一个 slot
+	cluster := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs: []string{"127.0.0.1:7000", "127.0.0.1:7001"},
+	})
+	defer cluster.Close()
+	_, err = NewCompositeLimiter(cluster,
+		Quota{Name: "a", Rate: 1, Period: time.Second},
+		Quota{Name: "b", Rate: 1, Period: time.Second},
+	)
+	assert.NotNil(t, err)
+	_, err = NewCompositeLimiter(cluster, Quota{Name: "a", Rate: 1, Period: time.Second})
+	assert.Nil(t, err)
+	_, err = NewCompositeLimiter(cluster,
+		Quota{Name: "a", Rate: 1, Period: time.Second},
+		Quota{Name: "b", Rate: 1, Period: time.Second, HashTag: true},
+	)
+	assert.Nil(t, err)
+}
@@ -7,16 +7,21 @@ import (
 )
 
 type Config struct {
-	RedisClient     *redis.Client // redis 客户端
-	Period          time.Duration // 限流周期
-	CleanupInterval time.Duration // 清理过期限流器的间隔，只在ModeRateLimit模型下使用
-	Rate            int           // 每个限流周期允许的最大请求数
-	Burst           int           // 令牌桶的最大容量
+	RedisClient     redis.UniversalClient // redis 客户端，支持单机、哨兵和集群
+	Period          time.Duration         // 限流周期
+	CleanupInterval time.Duration         // 清理过期限流器的间隔，只在降级为进程内限流时使用
+	Rate            int                   // 每个限流周期允许的最大请求数
+	Burst           int                   // 令牌桶的最大容量
+	Algorithm       Algorithm             // 限流算法，默认为 AlgorithmGCRA
+	Replicas        int                   // 共享配额的实例数，降级为进程内限流时每个实例使用 1/Replicas 的配额，默认为1
+	FailurePolicy   FailurePolicy         // Redis 不可用时的降级策略，默认为 FailureLocal
+	OnModeChange    ModeChangeFunc        // 限流器在 Redis 和降级模式之间切换时的回调
 }
 
 type Option func(*Config)
 
-func WithRedisClient(client *redis.Client) Option {
+// WithRedisClient 设置 Redis 客户端，集群模式使用 *redis.ClusterClient，哨兵模式使用 redis.NewFailoverClient 创建的客户端
+func WithRedisClient(client redis.UniversalClient) Option {
 	return func(cfg *Config) {
 		cfg.RedisClient = client
 	}
@@ -49,3 +54,31 @@ func WithBurst(burst int) Option {
 		cfg.Burst = burst
 	}
 }
+
+// WithAlgorithm 设置限流算法
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(cfg *Config) {
+		cfg.Algorithm = algorithm
+	}
+}
+
+// WithReplicas 设置共享配额的实例数，降级为进程内限流时按实例数均分配额
+func WithReplicas(replicas int) Option {
+	return func(cfg *Config) {
+		cfg.Replicas = replicas
+	}
+}
+
+// WithFailurePolicy 设置 Redis 不可用时的降级策略
+func WithFailurePolicy(policy FailurePolicy) Option {
+	return func(cfg *Config) {
+		cfg.FailurePolicy = policy
+	}
+}
+
+// WithOnModeChange 设置限流器切换模式时的回调
+func WithOnModeChange(fn ModeChangeFunc) Option {
+	return func(cfg *Config) {
+		cfg.OnModeChange = fn
+	}
+}
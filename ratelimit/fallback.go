@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+)
+
+// Mode 限流器的工作模式
+type Mode string
+
+const (
+	ModeRedis    Mode = "redis"    // 使用 Redis 限流，所有实例共享配额
+	ModeFallback Mode = "fallback" // Redis 不可用，按 FailurePolicy 降级
+)
+
+// ModeChangeFunc 限流器切换模式时的回调，切换到 ModeFallback 时 err 为导致降级的错误，恢复时 err 为 nil
+type ModeChangeFunc func(from, to Mode, err error)
+
+// FailurePolicy Redis 不可用时的降级策略
+type FailurePolicy string
+
+const (
+	// FailureLocal 降级为相同算法的进程内限流，配额按 Replicas 均分
+	FailureLocal FailurePolicy = "local"
+	// FailureOpen 放行所有请求
+	FailureOpen FailurePolicy = "open"
+	// FailureClosed 拒绝所有请求，RetryAfter 为 Redis 的探活间隔
+	FailureClosed FailurePolicy = "closed"
+)
+
+// newFallbackAlgorithm 按降级策略创建 Redis 不可用时使用的限流算法
+func newFallbackAlgorithm(cfg *Config) (algorithm, error) {
+	switch cfg.FailurePolicy {
+	case FailureLocal:
+		return newLocalAlgorithm(cfg), nil
+	case FailureOpen:
+		return &staticAlgorithm{allowed: true, remaining: cfg.Rate}, nil
+	case FailureClosed:
+		return &staticAlgorithm{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit failure policy: %s", cfg.FailurePolicy)
+	}
+}
+
+// staticAlgorithm 固定放行或拒绝所有请求
+type staticAlgorithm struct {
+	allowed   bool
+	remaining int
+}
+
+func (a *staticAlgorithm) allowN(ctx context.Context, key string, n int) (Result, error) {
+	if a.allowed {
+		return Result{Allowed: true, Remaining: a.remaining}, nil
+	}
+	return Result{RetryAfter: pingInterval}, nil
+}
+
+func (a *staticAlgorithm) close() {}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallback(t *testing.T) {
+	// 不可达的 Redis，请求会立即失败并降级
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: time.Millisecond * 100,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	t.Run("local honours rate and replicas", func(t *testing.T) {
+		limiter, err := NewLimiter(WithRedisClient(client), WithRate(10), WithBurst(10), WithReplicas(2))
+		assert.Nil(t, err)
+		defer limiter.Close()
+
+		key := "test_ratelimit_" + uuid.NewString()
+		allowed := 0
+		for i := 0; i < 10; i++ {
+			ok, err := limiter.Allow(ctx, key)
+			assert.Nil(t, err)
+			if ok {
+				allowed++
+			}
+		}
+		assert.Equal(t, 5, allowed)
+		assert.Equal(t, ModeFallback, limiter.Mode())
+	})
+
+	t.Run("open", func(t *testing.T) {
+		limiter, err := NewLimiter(WithRedisClient(client), WithFailurePolicy(FailureOpen))
+		assert.Nil(t, err)
+		defer limiter.Close()
+
+		for i := 0; i < 3; i++ {
+			ok, err := limiter.Allow(ctx, "test_ratelimit_open")
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		limiter, err := NewLimiter(WithRedisClient(client), WithFailurePolicy(FailureClosed))
+		assert.Nil(t, err)
+		defer limiter.Close()
+
+		res, err := limiter.AllowN(ctx, "test_ratelimit_closed", 1)
+		assert.Nil(t, err)
+		assert.False(t, res.Allowed)
+		assert.Equal(t, pingInterval, res.RetryAfter)
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		_, err := NewLimiter(WithRedisClient(client), WithFailurePolicy("unknown"))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("mode change", func(t *testing.T) {
+		var mu sync.Mutex
+		var changes [][2]Mode
+		limiter, err := NewLimiter(WithRedisClient(client), WithOnModeChange(func(from, to Mode, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, [2]Mode{from, to})
+		}))
+		assert.Nil(t, err)
+		defer limiter.Close()
+
+		assert.Equal(t, ModeRedis, limiter.Mode())
+		_, err = limiter.Allow(ctx, "test_ratelimit_mode")
+		assert.Nil(t, err)
+		_, err = limiter.Allow(ctx, "test_ratelimit_mode")
+		assert.Nil(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, [][2]Mode{{ModeRedis, ModeFallback}}, changes)
+	})
+}
@@ -4,20 +4,38 @@ import (
 	"context"
 	"errors"
 	"time"
-
-	"github.com/go-redis/redis_rate/v10"
 )
 
 type Limiter interface {
-	Allow(ctx context.Context, key string) (bool, error) // 是否允许请求
+	Allow(ctx context.Context, key string) (bool, error)           // 是否允许请求
+	AllowN(ctx context.Context, key string, n int) (Result, error) // 是否允许 n 个请求，返回剩余配额等信息
+	Wait(ctx context.Context, key string) error                    // 阻塞直到允许请求或 ctx 结束
+	Mode() Mode                                                    // 当前的工作模式
+	Close() error                                                  // 停止后台协程
+}
+
+// Result 限流结果，可用于设置 X-RateLimit-* 和 Retry-After 响应头
+type Result struct {
+	Allowed    bool          // 是否允许本次请求
+	Limit      int           // 每个限流周期允许的最大请求数，并发限制时为最大并发数
+	Remaining  int           // 剩余可用的请求数
+	RetryAfter time.Duration // 被拒绝时距离可以重试的时间，允许时为0，为-1时表示请求数超过限制，永远无法满足
+	ResetAfter time.Duration // 距离限流状态完全恢复的时间
+	Lease      *Lease        // AlgorithmConcurrency 允许请求时返回的占用，请求结束后需要调用 Release 释放
 }
 
+// NewLimiter 创建基于 Redis 的限流器，Redis 不可用时按 FailurePolicy 降级，默认降级为相同算法的进程内限流
+// 使用 AlgorithmConcurrency 时需要通过 AllowN 获取 Result.Lease，或使用 Acquirer 获取 Lease，请求结束后调用 Release 释放，
+// Allow 和 Wait 无法返回占用，会直接返回错误
 func NewLimiter(opts ...Option) (Limiter, error) {
 	cfg := &Config{
-		Rate:            1,           // 默认每秒一个请求
-		Burst:           1,           // 默认容量为1
-		Period:          time.Second, // 默认时间窗口为1秒
-		CleanupInterval: time.Minute, // 默认清理间隔为1分钟
+		Rate:            1,             // 默认每秒一个请求
+		Burst:           1,             // 默认容量为1
+		Period:          time.Second,   // 默认时间窗口为1秒
+		CleanupInterval: time.Minute,   // 默认清理间隔为1分钟
+		Algorithm:       AlgorithmGCRA, // 默认使用令牌桶算法
+		Replicas:        1,             // 默认单实例
+		FailurePolicy:   FailureLocal,  // 默认降级为进程内限流
 	}
 	for _, opt := range opts {
 		opt(cfg)
@@ -25,13 +43,22 @@ func NewLimiter(opts ...Option) (Limiter, error) {
 	if cfg.RedisClient == nil {
 		return nil, errors.New("redis client is nil")
 	}
+	remote, err := newRedisAlgorithm(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rescue, err := newFallbackAlgorithm(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &redisLimiter{
-		limiter:       redis_rate.NewLimiter(cfg.RedisClient),
+		limiter:       remote,
 		client:        cfg.RedisClient,
 		rate:          cfg.Rate,
-		burst:         cfg.Burst,
-		period:        cfg.Period,
+		algorithm:     cfg.Algorithm,
 		redisAlive:    1,
-		rescueLimiter: newTimeRateLimiter(cfg.Period, cfg.Burst, cfg.CleanupInterval),
+		rescueLimiter: rescue,
+		onModeChange:  cfg.OnModeChange,
+		closeChan:     make(chan struct{}),
 	}, nil
 }
@@ -0,0 +1,323 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// localStore 进程内按 key 保存限流状态，定期清理长时间未访问的 key
+type localStore[T any] struct {
+	mu              sync.Mutex
+	entries         map[string]*localEntry[T]
+	cleanupInterval time.Duration // 清理过期限流状态的间隔
+	stopChan        chan struct{}
+	stopOnce        sync.Once
+}
+
+type localEntry[T any] struct {
+	state        T
+	lastAccessed time.Time
+}
+
+func newLocalStore[T any](cleanupInterval time.Duration) *localStore[T] {
+	store := &localStore[T]{
+		entries:         make(map[string]*localEntry[T]),
+		cleanupInterval: cleanupInterval,
+		stopChan:        make(chan struct{}),
+	}
+
+	go store.cleanupLoop()
+
+	return store
+}
+
+// update 在锁内读取并修改 key 的限流状态
+func (s *localStore[T]) update(key string, fn func(state *T, now time.Time) Result) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &localEntry[T]{}
+		s.entries[key] = entry
+	}
+	entry.lastAccessed = now
+	return fn(&entry.state, now)
+}
+
+// 清理过期的限流状态
+func (s *localStore[T]) cleanupLoop() {
+	ticker := time.NewTicker(s.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanupExpired()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// close 停止清理协程
+func (s *localStore[T]) close() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+}
+
+func (s *localStore[T]) cleanupExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.entries {
+		if now.Sub(entry.lastAccessed) > s.cleanupInterval {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// localSlidingWindowLog 滑动窗口日志的进程内实现
+type localSlidingWindowLog struct {
+	store  *localStore[[]time.Time]
+	rate   int
+	period time.Duration
+}
+
+func newLocalSlidingWindowLog(rate int, period, cleanupInterval time.Duration) *localSlidingWindowLog {
+	return &localSlidingWindowLog{
+		store:  newLocalStore[[]time.Time](cleanupInterval),
+		rate:   rate,
+		period: period,
+	}
+}
+
+func (a *localSlidingWindowLog) allowN(ctx context.Context, key string, n int) (Result, error) {
+	return a.store.update(key, func(requests *[]time.Time, now time.Time) Result {
+		*requests = pruneBefore(*requests, now.Add(-a.period))
+		count := len(*requests)
+		if count+n > a.rate {
+			res := Result{Remaining: max(a.rate-count, 0), RetryAfter: -1}
+			if n <= a.rate {
+				res.RetryAfter = (*requests)[count+n-a.rate-1].Add(a.period).Sub(now)
+			}
+			if count > 0 {
+				res.ResetAfter = (*requests)[count-1].Add(a.period).Sub(now)
+			}
+			return res
+		}
+		for i := 0; i < n; i++ {
+			*requests = append(*requests, now)
+		}
+		return Result{Allowed: true, Remaining: a.rate - count - n, ResetAfter: a.period}
+	}), nil
+}
+
+func (a *localSlidingWindowLog) close() {
+	a.store.close()
+}
+
+// slidingWindowCounter 滑动窗口计数的状态
+type slidingWindowCounter struct {
+	window   int64 // 当前窗口序号
+	current  int   // 当前窗口内的请求数
+	previous int   // 上一个窗口内的请求数
+}
+
+// localSlidingWindowCounter 滑动窗口计数的进程内实现
+type localSlidingWindowCounter struct {
+	store  *localStore[slidingWindowCounter]
+	rate   int
+	period time.Duration
+}
+
+func newLocalSlidingWindowCounter(rate int, period, cleanupInterval time.Duration) *localSlidingWindowCounter {
+	return &localSlidingWindowCounter{
+		store:  newLocalStore[slidingWindowCounter](cleanupInterval),
+		rate:   rate,
+		period: period,
+	}
+}
+
+func (a *localSlidingWindowCounter) allowN(ctx context.Context, key string, n int) (Result, error) {
+	return a.store.update(key, func(counter *slidingWindowCounter, now time.Time) Result {
+		window := now.UnixNano() / int64(a.period)
+		switch {
+		case window == counter.window+1:
+			counter.previous, counter.current = counter.current, 0
+		case window > counter.window+1:
+			counter.previous, counter.current = 0, 0
+		}
+		counter.window = window
+
+		period := float64(a.period)
+		elapsed := float64(now.UnixNano() % int64(a.period))
+		previous, current, limit := float64(counter.previous), float64(counter.current), float64(a.rate)
+		estimated := previous*(1-elapsed/period) + current
+		if estimated+float64(n) > limit {
+			res := Result{Remaining: max(int(limit-estimated), 0), RetryAfter: -1}
+			if n <= a.rate {
+				var retry float64
+				if counter.current+n <= a.rate {
+					// 等待上一个窗口滑出足够的比例
+					retry = period*(1-(limit-current-float64(n))/previous) - elapsed
+				} else {
+					// 等待进入下一个窗口，并且当前窗口滑出足够的比例
+					retry = period - elapsed + period*(1-(limit-float64(n))/current)
+				}
+				res.RetryAfter = max(time.Duration(retry), time.Millisecond)
+			}
+			if counter.current > 0 {
+				res.ResetAfter = time.Duration(period*2 - elapsed)
+			} else if counter.previous > 0 {
+				res.ResetAfter = time.Duration(period - elapsed)
+			}
+			return res
+		}
+		counter.current += n
+		return Result{
+			Allowed:    true,
+			Remaining:  int(limit - estimated - float64(n)),
+			ResetAfter: time.Duration(period*2 - elapsed),
+		}
+	}), nil
+}
+
+func (a *localSlidingWindowCounter) close() {
+	a.store.close()
+}
+
+// fixedWindow 固定窗口的状态
+type fixedWindow struct {
+	start time.Time // 窗口开始时间
+	count int       // 窗口内的请求数
+}
+
+// localFixedWindow 固定窗口的进程内实现
+type localFixedWindow struct {
+	store  *localStore[fixedWindow]
+	rate   int
+	period time.Duration
+}
+
+func newLocalFixedWindow(rate int, period, cleanupInterval time.Duration) *localFixedWindow {
+	return &localFixedWindow{
+		store:  newLocalStore[fixedWindow](cleanupInterval),
+		rate:   rate,
+		period: period,
+	}
+}
+
+func (a *localFixedWindow) allowN(ctx context.Context, key string, n int) (Result, error) {
+	return a.store.update(key, func(w *fixedWindow, now time.Time) Result {
+		if !now.Before(w.start.Add(a.period)) {
+			w.start, w.count = now, 0
+		}
+		reset := w.start.Add(a.period).Sub(now)
+		if w.count+n > a.rate {
+			res := Result{Remaining: max(a.rate-w.count, 0), RetryAfter: -1, ResetAfter: reset}
+			if n <= a.rate {
+				res.RetryAfter = reset
+			}
+			return res
+		}
+		w.count += n
+		return Result{Allowed: true, Remaining: a.rate - w.count, ResetAfter: reset}
+	}), nil
+}
+
+func (a *localFixedWindow) close() {
+	a.store.close()
+}
+
+// localConcurrency 并发限制的进程内实现，按过期时间顺序记录每个占用
+type localConcurrency struct {
+	store  *localStore[[]localLease]
+	rate   int
+	period time.Duration
+	nextID atomic.Uint64 // 占用的唯一标识
+}
+
+// localLease 进程内的单个占用
+type localLease struct {
+	id       uint64
+	expireAt time.Time
+}
+
+func newLocalConcurrency(rate int, period, cleanupInterval time.Duration) *localConcurrency {
+	return &localConcurrency{
+		store:  newLocalStore[[]localLease](cleanupInterval),
+		rate:   rate,
+		period: period,
+	}
+}
+
+func (a *localConcurrency) allowN(ctx context.Context, key string, n int) (Result, error) {
+	id := a.nextID.Add(1)
+	res := a.store.update(key, func(leases *[]localLease, now time.Time) Result {
+		*leases = pruneLeases(*leases, now)
+		count := len(*leases)
+		if count+n > a.rate {
+			res := Result{Remaining: max(a.rate-count, 0), RetryAfter: -1}
+			if n <= a.rate {
+				res.RetryAfter = (*leases)[count+n-a.rate-1].expireAt.Sub(now)
+			}
+			if count > 0 {
+				res.ResetAfter = (*leases)[count-1].expireAt.Sub(now)
+			}
+			return res
+		}
+		for i := 0; i < n; i++ {
+			*leases = append(*leases, localLease{id: id, expireAt: now.Add(a.period)})
+		}
+		return Result{Allowed: true, Remaining: a.rate - count - n, ResetAfter: a.period}
+	})
+	if res.Allowed {
+		res.Lease = &Lease{release: func(ctx context.Context) error {
+			a.release(key, id)
+			return nil
+		}}
+	}
+	return res, nil
+}
+
+// release 只删除标识为 id 的占用
+func (a *localConcurrency) release(key string, id uint64) {
+	a.store.update(key, func(leases *[]localLease, now time.Time) Result {
+		kept := (*leases)[:0]
+		for _, lease := range *leases {
+			if lease.id != id {
+				kept = append(kept, lease)
+			}
+		}
+		*leases = kept
+		return Result{}
+	})
+}
+
+func (a *localConcurrency) close() {
+	a.store.close()
+}
+
+// pruneLeases 删除按过期时间排序的占用中已过期的部分
+func pruneLeases(leases []localLease, now time.Time) []localLease {
+	i := 0
+	for i < len(leases) && !leases[i].expireAt.After(now) {
+		i++
+	}
+	return leases[i:]
+}
+
+// pruneBefore 删除有序时间列表中不晚于 deadline 的时间
+func pruneBefore(times []time.Time, deadline time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(deadline) {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/morehao/golib/gcontext/gincontext"
+	"github.com/morehao/golib/gerror"
+	"github.com/morehao/golib/glog"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTooManyRequests 请求被限流时返回的默认错误
+var ErrTooManyRequests = gerror.Error{Code: http.StatusTooManyRequests, Msg: "too many requests"}
+
+// 限流相关的响应头
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// KeyFunc 从请求中提取限流 key，返回空字符串时不限流
+type KeyFunc func(c *gin.Context) string
+
+// KeyByClientIP 按客户端 IP 限流
+func KeyByClientIP() KeyFunc {
+	return func(c *gin.Context) string {
+		return "ip:" + gincontext.GetClientIp(c)
+	}
+}
+
+// KeyByUserID 按用户 ID 限流，未登录的请求按客户端 IP 限流
+func KeyByUserID() KeyFunc {
+	return func(c *gin.Context) string {
+		if userID := gincontext.GetUserID(c); userID != 0 {
+			return "user:" + strconv.FormatUint(uint64(userID), 10)
+		}
+		return "ip:" + gincontext.GetClientIp(c)
+	}
+}
+
+// KeyByHeader 按请求头的值限流，请求头为空时不限流
+func KeyByHeader(name string) KeyFunc {
+	return func(c *gin.Context) string {
+		value := c.GetHeader(name)
+		if value == "" {
+			return ""
+		}
+		return "header:" + name + ":" + value
+	}
+}
+
+// KeyByRoute 按路由限流，同一路由的所有请求共享配额
+func KeyByRoute() KeyFunc {
+	return func(c *gin.Context) string {
+		return "route:" + c.Request.Method + ":" + c.FullPath()
+	}
+}
+
+// ParseKeyFunc 解析限流维度配置，支持 ip、user、route、header:<name>
+func ParseKeyFunc(spec string) (KeyFunc, error) {
+	switch {
+	case spec == "" || spec == "ip":
+		return KeyByClientIP(), nil
+	case spec == "user":
+		return KeyByUserID(), nil
+	case spec == "route":
+		return KeyByRoute(), nil
+	case strings.HasPrefix(spec, "header:") && len(spec) > len("header:"):
+		return KeyByHeader(strings.TrimPrefix(spec, "header:")), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit key: %s", spec)
+	}
+}
+
+// MiddlewareOption 是一个函数类型，用于设置限流中间件的选项
+type MiddlewareOption func(cfg *middlewareConfig)
+
+type middlewareConfig struct {
+	rejectErr gerror.Error
+}
+
+// WithRejectError 设置请求被限流时返回的错误
+func WithRejectError(err gerror.Error) MiddlewareOption {
+	return func(cfg *middlewareConfig) {
+		cfg.rejectErr = err
+	}
+}
+
+func newMiddlewareConfig(opts []MiddlewareOption) *middlewareConfig {
+	cfg := &middlewareConfig{
+		rejectErr: ErrTooManyRequests,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Middleware 使用 limiter 对所有请求限流，keyFunc 决定限流维度
+func Middleware(limiter Limiter, keyFunc KeyFunc, opts ...MiddlewareOption) gin.HandlerFunc {
+	cfg := newMiddlewareConfig(opts)
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		limit(c, limiter, key, cfg)
+	}
+}
+
+// RouteRule 路由限流规则
+type RouteRule struct {
+	Method    string        `yaml:"method"`    // 请求方法，为空时匹配所有方法
+	Path      string        `yaml:"path"`      // gin 注册的路由路径，如 /api/orders/:id，为空时匹配所有路由
+	Key       string        `yaml:"key"`       // 限流维度：ip、user、route、header:<name>，默认为 ip
+	Algorithm Algorithm     `yaml:"algorithm"` // 限流算法，默认为 gcra
+	Rate      int           `yaml:"rate"`      // 每个限流周期允许的最大请求数
+	Period    time.Duration `yaml:"period"`    // 限流周期，默认为1秒
+	Burst     int           `yaml:"burst"`     // 令牌桶的最大容量，默认与 Rate 相同
+}
+
+// RuleConfig 路由限流配置，可以通过 conf.LoadConfig 从 YAML 文件加载
+type RuleConfig struct {
+	KeyPrefix string      `yaml:"key_prefix"` // 限流 key 的前缀，key 为 prefix:method:path:维度
+	Rules     []RouteRule `yaml:"rules"`      // 按顺序匹配，使用第一条匹配的规则，没有匹配的规则时不限流
+}
+
+type routeLimiter struct {
+	rule    RouteRule
+	prefix  string
+	keyFunc KeyFunc
+	limiter Limiter
+}
+
+// RuleMiddleware 按路由规则限流，每条规则使用独立的限流器和配额
+// 返回的 closer 用于停止所有限流器的后台协程，服务退出或重新加载规则时需要调用
+func RuleMiddleware(client redis.UniversalClient, ruleCfg *RuleConfig, opts ...MiddlewareOption) (gin.HandlerFunc, func() error, error) {
+	cfg := newMiddlewareConfig(opts)
+	routes := make([]*routeLimiter, 0, len(ruleCfg.Rules))
+	closer := func() error {
+		var errs []error
+		for _, route := range routes {
+			errs = append(errs, route.limiter.Close())
+		}
+		return errors.Join(errs...)
+	}
+	for _, rule := range ruleCfg.Rules {
+		if rule.Rate <= 0 {
+			_ = closer()
+			return nil, nil, fmt.Errorf("rate of rule %s %s must be greater than 0", rule.Method, rule.Path)
+		}
+		keyFunc, err := ParseKeyFunc(rule.Key)
+		if err != nil {
+			_ = closer()
+			return nil, nil, err
+		}
+		limiterOpts := []Option{WithRedisClient(client), WithRate(rule.Rate), WithBurst(rule.Rate)}
+		if rule.Algorithm != "" {
+			limiterOpts = append(limiterOpts, WithAlgorithm(rule.Algorithm))
+		}
+		if rule.Period > 0 {
+			limiterOpts = append(limiterOpts, WithPeriod(rule.Period))
+		}
+		if rule.Burst > 0 {
+			limiterOpts = append(limiterOpts, WithBurst(rule.Burst))
+		}
+		limiter, err := NewLimiter(limiterOpts...)
+		if err != nil {
+			_ = closer()
+			return nil, nil, err
+		}
+
+		prefix := fmt.Sprintf("%s:%s:", orDefault(strings.ToUpper(rule.Method), "*"), orDefault(rule.Path, "*"))
+		if ruleCfg.KeyPrefix != "" {
+			prefix = ruleCfg.KeyPrefix + ":" + prefix
+		}
+		routes = append(routes, &routeLimiter{
+			rule:    rule,
+			prefix:  prefix,
+			keyFunc: keyFunc,
+			limiter: limiter,
+		})
+	}
+
+	return func(c *gin.Context) {
+		for _, route := range routes {
+			if !route.match(c) {
+				continue
+			}
+			key := route.keyFunc(c)
+			if key == "" {
+				break
+			}
+			limit(c, route.limiter, route.prefix+key, cfg)
+			return
+		}
+		c.Next()
+	}, closer, nil
+}
+
+func (r *routeLimiter) match(c *gin.Context) bool {
+	if r.rule.Method != "" && !strings.EqualFold(r.rule.Method, c.Request.Method) {
+		return false
+	}
+	return r.rule.Path == "" || r.rule.Path == c.FullPath()
+}
+
+// limit 执行限流并设置响应头，被限流时中断请求，限流器出错时放行
+func limit(c *gin.Context, limiter Limiter, key string, cfg *middlewareConfig) {
+	res, err := limiter.AllowN(c, key, 1)
+	if err != nil {
+		glog.Errorf(c, "[ratelimit] allow key %s failed: %v", key, err)
+		c.Next()
+		return
+	}
+
+	c.Header(HeaderLimit, strconv.Itoa(res.Limit))
+	c.Header(HeaderRemaining, strconv.Itoa(res.Remaining))
+	c.Header(HeaderReset, strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	if !res.Allowed {
+		if res.RetryAfter >= 0 {
+			c.Header(HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		}
+		gincontext.Abort(c, cfg.rejectErr)
+		return
+	}
+
+	// 并发限制在请求结束后释放占用
+	if res.Lease != nil {
+		defer func() {
+			if releaseErr := res.Lease.Release(context.WithoutCancel(c)); releaseErr != nil {
+				glog.Errorf(c, "[ratelimit] release key %s failed: %v", key, releaseErr)
+			}
+		}()
+	}
+	c.Next()
+}
+
+// ceilSeconds 将时间向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
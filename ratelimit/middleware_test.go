@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+		DB:   0,
+	})
+	defer client.Close()
+
+	limiter, err := NewLimiter(
+		WithRedisClient(client),
+		WithAlgorithm(AlgorithmFixedWindow),
+		WithRate(2),
+		WithPeriod(time.Second*10),
+	)
+	assert.Nil(t, err)
+	router := gin.New()
+	router.Use(Middleware(limiter, KeyByHeader("X-Api-Key")))
+	router.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	apiKey := uuid.NewString()
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(apiKey)
+	assert.Equal(t, "pong", w.Body.String())
+	assert.Equal(t, "2", w.Header().Get(HeaderLimit))
+	assert.Equal(t, "1", w.Header().Get(HeaderRemaining))
+	assert.Equal(t, "10", w.Header().Get(HeaderReset))
+	request(apiKey)
+
+	// 超过限制后返回限流错误和 Retry-After
+	w = request(apiKey)
+	assert.Contains(t, w.Body.String(), `"code":429`)
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+	assert.NotEmpty(t, w.Header().Get(HeaderRetryAfter))
+
+	// 没有 key 的请求不限流
+	w = request("")
+	assert.Equal(t, "pong", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderLimit))
+}
+
+func TestRuleMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := redis.NewClient(&redis.Options{
+		Addr: "127.0.0.1:6379",
+		DB:   0,
+	})
+	defer client.Close()
+
+	var ruleCfg RuleConfig
+	content := `
+key_prefix: test_rule_` + uuid.NewString() + `
+rules:
+  - method: POST
+    path: /orders/:id
+    key: header:X-User
+    algorithm: sliding_window_log
+    rate: 1
+    period: 10s
+  - path: /orders/:id
+    key: route
+    rate: 3
+    period: 10s
+`
+	assert.Nil(t, yaml.Unmarshal([]byte(content), &ruleCfg))
+	assert.Equal(t, time.Second*10, ruleCfg.Rules[0].Period)
+
+	middleware, closer, err := RuleMiddleware(client, &ruleCfg)
+	assert.Nil(t, err)
+	defer closer()
+	router := gin.New()
+	router.Use(middleware)
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	}
+	router.GET("/orders/:id", handler)
+	router.POST("/orders/:id", handler)
+	router.GET("/health", handler)
+
+	request := func(method, path, user string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// POST 按用户限流，每个用户独立计数
+	assert.Equal(t, "ok", request(http.MethodPost, "/orders/1", "alice"))
+	assert.Contains(t, request(http.MethodPost, "/orders/2", "alice"), `"code":429`)
+	assert.Equal(t, "ok", request(http.MethodPost, "/orders/1", "bob"))
+
+	// GET 匹配第二条规则，同一路由共享配额
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "ok", request(http.MethodGet, "/orders/"+uuid.NewString(), "alice"))
+	}
+	assert.Contains(t, request(http.MethodGet, "/orders/1", "bob"), `"code":429`)
+
+	// 没有匹配规则的路由不限流
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, "ok", request(http.MethodGet, "/health", "alice"))
+	}
+
+	_, _, err = RuleMiddleware(client, &RuleConfig{Rules: []RouteRule{{Path: "/", Key: "cookie", Rate: 1}}})
+	assert.NotNil(t, err)
+}
+
+func TestRuleMiddlewareClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 不可达的 Redis，请求后每个限流器都会启动探活协程
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		DialTimeout: time.Millisecond * 100,
+		MaxRetries:  -1,
+	})
+	defer client.Close()
+
+	before := runtime.NumGoroutine()
+	ruleCfg := &RuleConfig{Rules: []RouteRule{
+		{Method: http.MethodPost, Path: "/orders", Rate: 1},
+		{Path: "/orders", Key: "route", Rate: 1},
+	}}
+	middleware, closer, err := RuleMiddleware(client, ruleCfg)
+	assert.Nil(t, err)
+	router := gin.New()
+	router.Use(middleware)
+	router.Any("/orders", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	for _, method := range []string{http.MethodPost, http.MethodGet} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/orders", nil))
+	}
+	assert.Greater(t, runtime.NumGoroutine(), before)
+
+	// 关闭后清理协程和探活协程都退出
+	assert.Nil(t, closer())
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, time.Second, time.Millisecond*10)
+}
@@ -3,50 +3,153 @@ package ratelimit
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/go-redis/redis_rate/v10"
+	"github.com/morehao/golib/glog"
 	"github.com/redis/go-redis/v9"
 )
 
-const pingInterval = time.Millisecond * 100
+const (
+	pingInterval    = time.Millisecond * 100
+	minWaitInterval = time.Millisecond * 10 // Wait 重试的最短间隔
+	maxWaitInterval = time.Second           // Wait 重试的最长间隔，并发限制的占用可能提前释放
+)
 
 type redisLimiter struct {
-	limiter        *redis_rate.Limiter
-	client         *redis.Client
-	rate           int           // 限制周期内允许的最大请求数
-	burst          int           // 限制周期突发内允许的请求数
-	period         time.Duration // 限制周期
+	limiter        algorithm
+	client         redis.UniversalClient
+	rate           int // 每个限流周期允许的最大请求数
+	algorithm      Algorithm
 	rescueLock     sync.Mutex
 	redisAlive     uint32
 	monitorStarted bool
-	rescueLimiter  *timeRateLimiter
+	rescueLimiter  algorithm      // Redis 不可用时按 FailurePolicy 使用的限流算法
+	onModeChange   ModeChangeFunc // 切换模式时的回调
+	closeChan      chan struct{}
+	closeOnce      sync.Once
 }
 
+// Allow 是否允许请求，AlgorithmConcurrency 的占用需要释放，应使用 Acquire 或 AllowN
 func (l *redisLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	if l.algorithm == AlgorithmConcurrency {
+		return false, errors.New("concurrency limiter must use Acquire or AllowN")
+	}
+	res, err := l.AllowN(ctx, key, 1)
+	if err != nil {
+		return false, err
+	}
+	return res.Allowed, nil
+}
+
+func (l *redisLimiter) AllowN(ctx context.Context, key string, n int) (Result, error) {
+	if n <= 0 {
+		return Result{}, errors.New("n must be greater than 0")
+	}
+	res, err := l.allowN(ctx, key, n)
+	if err != nil {
+		return Result{}, err
+	}
+	res.Limit = l.rate
+	return res, nil
+}
+
+// Acquire 获取 AlgorithmConcurrency 的一个并发占用，被限流时返回 nil，请求结束后需要调用 Lease.Release 释放
+func (l *redisLimiter) Acquire(ctx context.Context, key string) (*Lease, error) {
+	if l.algorithm != AlgorithmConcurrency {
+		return nil, fmt.Errorf("acquire is not supported by algorithm %s", l.algorithm)
+	}
+	res, err := l.AllowN(ctx, key, 1)
+	if err != nil {
+		return nil, err
+	}
+	return res.Lease, nil
+}
+
+func (l *redisLimiter) allowN(ctx context.Context, key string, n int) (Result, error) {
 	if atomic.LoadUint32(&l.redisAlive) == 0 {
-		return l.rescueLimiter.Allow(ctx, key), nil
+		return l.rescueLimiter.allowN(ctx, key, n)
 	}
 
-	res, err := l.limiter.Allow(ctx, key, redis_rate.Limit{
-		Rate:   l.rate,
-		Period: l.period,
-		Burst:  l.burst,
-	})
+	res, err := l.limiter.allowN(ctx, key, n)
 	if errors.Is(err, redis.Nil) {
-		return false, nil
+		return Result{}, nil
 	}
 	if err != nil {
-		l.startMonitor()
-		return l.rescueLimiter.Allow(ctx, key), nil
+		l.startMonitor(ctx, err)
+		return l.rescueLimiter.allowN(ctx, key, n)
 	}
 
-	return res.Allowed > 0, nil
+	return res, nil
+}
+
+// Wait 阻塞直到允许请求，AlgorithmConcurrency 的占用需要释放，应使用 WaitAcquire
+func (l *redisLimiter) Wait(ctx context.Context, key string) error {
+	if l.algorithm == AlgorithmConcurrency {
+		return errors.New("concurrency limiter must use WaitAcquire")
+	}
+	_, err := l.wait(ctx, key)
+	return err
 }
 
-func (l *redisLimiter) startMonitor() {
+// WaitAcquire 阻塞直到获取 AlgorithmConcurrency 的一个并发占用，请求结束后需要调用 Lease.Release 释放
+func (l *redisLimiter) WaitAcquire(ctx context.Context, key string) (*Lease, error) {
+	if l.algorithm != AlgorithmConcurrency {
+		return nil, fmt.Errorf("acquire is not supported by algorithm %s", l.algorithm)
+	}
+	res, err := l.wait(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return res.Lease, nil
+}
+
+// wait 按 RetryAfter 等待后重试，直到允许请求或 ctx 结束
+func (l *redisLimiter) wait(ctx context.Context, key string) (Result, error) {
+	for {
+		res, err := l.AllowN(ctx, key, 1)
+		if err != nil {
+			return Result{}, err
+		}
+		if res.Allowed {
+			return res, nil
+		}
+		if res.RetryAfter < 0 {
+			return Result{}, fmt.Errorf("rate limit of key %s can never be satisfied", key)
+		}
+
+		wait := min(max(res.RetryAfter, minWaitInterval), maxWaitInterval)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Result{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Mode 返回限流器当前的工作模式
+func (l *redisLimiter) Mode() Mode {
+	if atomic.LoadUint32(&l.redisAlive) == 0 {
+		return ModeFallback
+	}
+	return ModeRedis
+}
+
+// Close 停止 Redis 探活和进程内限流的清理协程，关闭后不应继续使用限流器
+func (l *redisLimiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+		l.rescueLimiter.close()
+	})
+	return nil
+}
+
+// startMonitor 切换到降级模式，并在后台探测 Redis 是否恢复
+func (l *redisLimiter) startMonitor(ctx context.Context, cause error) {
 	l.rescueLock.Lock()
 	defer l.rescueLock.Unlock()
 
@@ -56,6 +159,8 @@ func (l *redisLimiter) startMonitor() {
 
 	l.monitorStarted = true
 	atomic.StoreUint32(&l.redisAlive, 0)
+	glog.Warnf(ctx, "[ratelimit] redis unavailable, switch to fallback mode: %v", cause)
+	l.notifyModeChange(ModeRedis, ModeFallback, cause)
 
 	go l.waitForRedis()
 }
@@ -69,10 +174,35 @@ func (l *redisLimiter) waitForRedis() {
 		l.rescueLock.Unlock()
 	}()
 
-	for range ticker.C {
-		if err := l.client.Ping(context.Background()).Err(); err == nil {
+	ctx := context.Background()
+	for {
+		select {
+		case <-l.closeChan:
+			return
+		case <-ticker.C:
+			if err := pingRedis(ctx, l.client); err != nil {
+				continue
+			}
 			atomic.StoreUint32(&l.redisAlive, 1)
+			glog.Infof(ctx, "[ratelimit] redis recovered, switch to redis mode")
+			l.notifyModeChange(ModeFallback, ModeRedis, nil)
 			return
 		}
 	}
 }
+
+func (l *redisLimiter) notifyModeChange(from, to Mode, err error) {
+	if l.onModeChange != nil {
+		l.onModeChange(from, to, err)
+	}
+}
+
+// pingRedis 检查 Redis 是否可用，集群模式下所有主节点都可用时才认为 Redis 已恢复
+func pingRedis(ctx context.Context, client redis.UniversalClient) error {
+	if cluster, ok := client.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return shard.Ping(ctx).Err()
+		})
+	}
+	return client.Ping(ctx).Err()
+}
@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// 脚本参数统一为 ARGV[1] 周期内允许的请求数，ARGV[2] 周期（毫秒），ARGV[3] 本次请求的唯一标识，ARGV[4] 本次请求数，
+// 使用 Redis 服务端时间计算窗口，避免各实例之间的时钟偏差，
+// 返回 {是否允许, 剩余请求数, 重试等待时间（毫秒）, 完全恢复时间（毫秒）}，请求数超过限制时重试等待时间为-1
+
+// slidingWindowLogScript 滑动窗口日志，zset 中 member 为请求标识，score 为请求时间
+var slidingWindowLogScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local n = tonumber(ARGV[4])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - period)
+local count = redis.call("ZCARD", KEYS[1])
+if count + n > limit then
+	local retry = -1
+	if n <= limit then
+		local oldest = redis.call("ZRANGE", KEYS[1], count + n - limit - 1, count + n - limit - 1, "WITHSCORES")
+		retry = tonumber(oldest[2]) + period - now
+	end
+	local reset = 0
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	if newest[2] then
+		reset = tonumber(newest[2]) + period - now
+	end
+	return {0, math.max(limit - count, 0), retry, reset}
+end
+for i = 1, n do
+	redis.call("ZADD", KEYS[1], now, ARGV[3] .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], period)
+return {1, limit - count - n, 0, period}
+`)
+
+// slidingWindowCounterScript 滑动窗口计数，hash 中 field 为窗口序号，value 为窗口内的请求数，
+// 当前周期的请求数按上一个窗口未滑出的比例加权估算
+var slidingWindowCounterScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local n = tonumber(ARGV[4])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local window = math.floor(now / period)
+local elapsed = now % period
+local current = tonumber(redis.call("HGET", KEYS[1], tostring(window)) or "0")
+local previous = tonumber(redis.call("HGET", KEYS[1], tostring(window - 1)) or "0")
+local estimated = previous * (1 - elapsed / period) + current
+if estimated + n > limit then
+	local retry = -1
+	if n <= limit then
+		if current + n <= limit then
+			-- 等待上一个窗口滑出足够的比例
+			retry = period * (1 - (limit - current - n) / previous) - elapsed
+		else
+			-- 等待进入下一个窗口，并且当前窗口滑出足够的比例
+			retry = period - elapsed + period * (1 - (limit - n) / current)
+		end
+		retry = math.max(math.ceil(retry), 1)
+	end
+	local reset = 0
+	if current > 0 then
+		reset = period * 2 - elapsed
+	elseif previous > 0 then
+		reset = period - elapsed
+	end
+	return {0, math.max(math.floor(limit - estimated), 0), retry, reset}
+end
+redis.call("HINCRBY", KEYS[1], tostring(window), n)
+for _, field in ipairs(redis.call("HKEYS", KEYS[1])) do
+	if tonumber(field) < window - 1 then
+		redis.call("HDEL", KEYS[1], field)
+	end
+end
+redis.call("PEXPIRE", KEYS[1], period * 2)
+return {1, math.floor(limit - estimated - n), 0, period * 2 - elapsed}
+`)
+
+// fixedWindowScript 固定窗口，窗口从第一个请求开始，过期后重新计数
+var fixedWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local n = tonumber(ARGV[4])
+local count = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ttl = redis.call("PTTL", KEYS[1])
+if count + n > limit then
+	local reset = math.max(ttl, 0)
+	local retry = -1
+	if n <= limit then
+		retry = reset
+	end
+	return {0, math.max(limit - count, 0), retry, reset}
+end
+count = redis.call("INCRBY", KEYS[1], n)
+if count == n or ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], period)
+	ttl = period
+end
+return {1, limit - count, 0, ttl}
+`)
+
+// concurrencyAcquireScript 并发限制，zset 中 member 为请求标识，score 为占用的过期时间
+var concurrencyAcquireScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local n = tonumber(ARGV[4])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+local count = redis.call("ZCARD", KEYS[1])
+if count + n > limit then
+	local retry = -1
+	if n <= limit then
+		local lease = redis.call("ZRANGE", KEYS[1], count + n - limit - 1, count + n - limit - 1, "WITHSCORES")
+		retry = tonumber(lease[2]) - now
+	end
+	local reset = 0
+	local latest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	if latest[2] then
+		reset = tonumber(latest[2]) - now
+	end
+	return {0, math.max(limit - count, 0), retry, reset}
+end
+for i = 1, n do
+	redis.call("ZADD", KEYS[1], now + period, ARGV[3] .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], period)
+return {1, limit - count - n, 0, period}
+`)
+
+// newMember 生成请求的唯一标识
+func newMember() string {
+	return uuid.NewString()
+}
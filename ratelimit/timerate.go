@@ -12,19 +12,22 @@ type timeRateLimiter struct {
 	mu              sync.Mutex
 	limiterMap      map[string]*rate.Limiter
 	lastAccessedMap map[string]time.Time // 记录每个key的最后访问时间
-	period          time.Duration        // 限制周期
+	limit           rate.Limit           // 每秒生成的令牌数
 	burst           int                  // 限制周期突发内允许的请求数
 	cleanupInterval time.Duration        // 清理过期限流器的间隔
+	stopChan        chan struct{}
+	stopOnce        sync.Once
 }
 
 // newTimeRateLimiter 创建一个新的 timeRateLimiter
-func newTimeRateLimiter(period time.Duration, burst int, cleanupInterval time.Duration) *timeRateLimiter {
+func newTimeRateLimiter(limit rate.Limit, burst int, cleanupInterval time.Duration) *timeRateLimiter {
 	limiter := &timeRateLimiter{
 		limiterMap:      make(map[string]*rate.Limiter),
 		lastAccessedMap: make(map[string]time.Time),
-		period:          period,
+		limit:           limit,
 		burst:           burst,
 		cleanupInterval: cleanupInterval,
+		stopChan:        make(chan struct{}),
 	}
 
 	go limiter.cleanupLoop()
@@ -38,7 +41,7 @@ func (l *timeRateLimiter) Allow(ctx context.Context, key string) bool {
 
 	limiter, ok := l.limiterMap[key]
 	if !ok {
-		limiter = rate.NewLimiter(rate.Every(l.period), l.burst)
+		limiter = rate.NewLimiter(l.limit, l.burst)
 		l.limiterMap[key] = limiter
 	}
 	l.lastAccessedMap[key] = time.Now()
@@ -46,10 +49,57 @@ func (l *timeRateLimiter) Allow(ctx context.Context, key string) bool {
 	return limiter.Allow()
 }
 
+func (l *timeRateLimiter) allowN(ctx context.Context, key string, n int) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiterMap[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiterMap[key] = limiter
+	}
+	now := time.Now()
+	l.lastAccessedMap[key] = now
+
+	reservation := limiter.ReserveN(now, n)
+	if !reservation.OK() {
+		// 请求数超过令牌桶容量
+		return Result{Remaining: int(limiter.TokensAt(now)), RetryAfter: -1, ResetAfter: l.resetAfter(limiter, now)}, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return Result{Remaining: max(int(limiter.TokensAt(now)), 0), RetryAfter: delay, ResetAfter: l.resetAfter(limiter, now)}, nil
+	}
+	return Result{Allowed: true, Remaining: int(limiter.TokensAt(now)), ResetAfter: l.resetAfter(limiter, now)}, nil
+}
+
+// resetAfter 令牌桶重新装满的时间
+func (l *timeRateLimiter) resetAfter(limiter *rate.Limiter, now time.Time) time.Duration {
+	missing := float64(l.burst) - limiter.TokensAt(now)
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(limiter.Limit()) * float64(time.Second))
+}
+
+func (l *timeRateLimiter) close() {
+	l.stopOnce.Do(func() {
+		close(l.stopChan)
+	})
+}
+
 // 清理过期的限流器实例
 func (l *timeRateLimiter) cleanupLoop() {
-	for range time.Tick(l.cleanupInterval) {
-		l.cleanupExpiredLimiters()
+	ticker := time.NewTicker(l.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.cleanupExpiredLimiters()
+		case <-l.stopChan:
+			return
+		}
 	}
 }
 
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 func TestTimeRateAllow(t *testing.T) {
 
-	limiter := newTimeRateLimiter(time.Second, 1, time.Minute)
+	limiter := newTimeRateLimiter(rate.Every(time.Second), 1, time.Minute)
 
 	ctx := context.Background()
 
@@ -1,26 +1,48 @@
 package dbmysql
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"sort"
 	"time"
 
 	"github.com/morehao/golib/glog"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 type MysqlConfig struct {
-	Addr          string        `yaml:"addr"`           // 地址
-	Database      string        `yaml:"database"`       // 数据库名
-	User          string        `yaml:"user"`           // 用户名
-	Password      string        `yaml:"password"`       // 密码
-	Charset       string        `yaml:"charset"`        // 字符集
-	Timeout       time.Duration `yaml:"timeout"`        // 连接超时
-	ReadTimeout   time.Duration `yaml:"read_timeout"`   // 读取超时
-	WriteTimeout  time.Duration `yaml:"write_timeout"`  // 写入超时
-	SlowThreshold time.Duration `yaml:"slow_threshold"` // 慢SQL阈值
-	MaxSqlLen     int           `yaml:"max_sql_len"`    // 日志最大SQL长度
-	loggerConfig  *glog.LogConfig
+	Addr          string          `yaml:"addr"`            // 地址
+	Database      string          `yaml:"database"`        // 数据库名
+	User          string          `yaml:"user"`            // 用户名
+	Password      string          `yaml:"password"`        // 密码
+	Charset       string          `yaml:"charset"`         // 字符集
+	Timeout       time.Duration   `yaml:"timeout"`         // 连接超时
+	ReadTimeout   time.Duration   `yaml:"read_timeout"`    // 读取超时
+	WriteTimeout  time.Duration   `yaml:"write_timeout"`   // 写入超时
+	SlowThreshold time.Duration   `yaml:"slow_threshold"`  // 慢SQL阈值
+	MaxSqlLen     int             `yaml:"max_sql_len"`     // 日志最大SQL长度
+	LogLevel      LogLevel        `yaml:"log_level"`       // SQL 日志级别，默认为 info，记录所有语句
+	LogSampleRate float64         `yaml:"log_sample_rate"` // 执行成功且未超过慢SQL阈值的语句的日志采样率，取值 (0, 1]，默认为1
+	Replicas      []ReplicaConfig `yaml:"replicas"`        // 从库，配置后查询路由到从库，写入和事务使用主库
+	ReplicaPolicy ReplicaPolicy   `yaml:"replica_policy"`  // 从库的负载均衡策略，默认为 random
+	// 连接池配置，主库和每个从库分别使用相同的配置，为0时使用默认值，为负数时不限制
+	MaxOpenConns    int               `yaml:"max_open_conns"`     // 最大打开连接数，默认为100
+	MaxIdleConns    int               `yaml:"max_idle_conns"`     // 最大空闲连接数，默认为10
+	ConnMaxLifetime time.Duration     `yaml:"conn_max_lifetime"`  // 连接最长使用时间，默认为1小时
+	ConnMaxIdleTime time.Duration     `yaml:"conn_max_idle_time"` // 连接最长空闲时间，默认为10分钟
+	TLS             *TLSConfig        `yaml:"tls"`                // TLS 配置，为空时不使用 TLS
+	Params          map[string]string `yaml:"params"`             // 额外的 DSN 参数，如 interpolateParams、collation
+	// 启动时连接失败的重试次数，默认为3，为负数时不重试
+	ConnectRetry int `yaml:"connect_retry"`
+	// 启动时第一次重试的等待时间，之后每次翻倍，最长10秒，默认为500毫秒
+	ConnectBackoff time.Duration `yaml:"connect_backoff"`
+	// 健康检查的间隔，为0时不检查，检查失败和恢复时记录日志，结果通过 Healthy 获取
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+	loggerConfig        *glog.LogConfig
 }
 
 type Option interface {
@@ -33,43 +55,124 @@ func (opt optionFunc) apply(cfg *MysqlConfig) {
 	opt(cfg)
 }
 
+// InitMysql 创建 MySQL 连接，启动时连接失败会按 ConnectRetry 重试，不再使用时调用 Close 关闭，
+// 选项和默认值作用于 cfg 的副本，不会修改传入的配置
 func InitMysql(cfg *MysqlConfig, opts ...Option) (*gorm.DB, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("mysql config is nil")
+	}
 	if cfg.Database == "" {
 		return nil, fmt.Errorf("database name is empty")
 	}
 
+	cfgCopy := *cfg
+	cfg = &cfgCopy
 	cfg.loggerConfig = glog.GetDefaultLogConfig()
 	for _, opt := range opts {
 		opt.apply(cfg)
 	}
-	dns := cfg.buildDns()
+	cfg.applyDefaults()
+	if err := cfg.registerTLS(); err != nil {
+		return nil, err
+	}
+	policy, err := cfg.replicaPolicy()
+	if err != nil {
+		return nil, err
+	}
+	logLevel, err := cfg.LogLevel.gormLogLevel()
+	if err != nil {
+		return nil, err
+	}
 	customLogger, newLogErr := newOrmLogger(&ormConfig{
-		Addr:         cfg.Addr,
-		Database:     cfg.Database,
-		MaxSqlLen:    cfg.MaxSqlLen,
-		loggerConfig: cfg.loggerConfig,
+		Addr:          cfg.Addr,
+		Database:      cfg.Database,
+		MaxSqlLen:     cfg.MaxSqlLen,
+		SlowThreshold: cfg.SlowThreshold,
+		LogLevel:      logLevel,
+		SampleRate:    cfg.LogSampleRate,
+		loggerConfig:  cfg.loggerConfig,
 	})
 	if newLogErr != nil {
 		return nil, newLogErr
 	}
-	db, err := gorm.Open(mysql.Open(dns), &gorm.Config{
-		Logger: customLogger,
-	})
+
+	var db *gorm.DB
+	var resolver *dbresolver.DBResolver
+	backoff := cfg.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		db, resolver, err = cfg.open(customLogger, policy)
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetry {
+			return nil, err
+		}
+		glog.Warnf(context.Background(), "[dbmysql] connect %s/%s failed, retry after %s: %v", cfg.Addr, cfg.Database, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	ins := &instance{
+		resolver: resolver,
+		stopChan: make(chan struct{}),
+	}
+	ins.healthy.Store(true)
+	instances.Store(sqlDB, ins)
+	if cfg.HealthCheckInterval > 0 {
+		go cfg.healthCheckLoop(db, ins)
+	}
 	return db, nil
 }
 
+// open 连接主库和从库并设置连接池
+func (cfg *MysqlConfig) open(gormLogger logger.Interface, policy dbresolver.Policy) (*gorm.DB, *dbresolver.DBResolver, error) {
+	db, err := gorm.Open(mysql.Open(cfg.buildDns()), &gorm.Config{
+		Logger: gormLogger,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.configurePool(sqlDB)
+	resolver, err := cfg.useReplicas(db, policy)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, nil, err
+	}
+	return db, resolver, nil
+}
+
 func (cfg *MysqlConfig) buildDns() string {
+	return cfg.buildDnsWithAddr(cfg.Addr)
+}
+
+func (cfg *MysqlConfig) buildDnsWithAddr(addr string) string {
 	dns := fmt.Sprintf("%s:%s@tcp(%s)/%s?&parseTime=True&loc=Local&timeout=%s&readTimeout=%s&writeTimeout=%s",
-		cfg.User, cfg.Password, cfg.Addr, cfg.Database,
+		cfg.User, cfg.Password, addr, cfg.Database,
 		cfg.Timeout, cfg.ReadTimeout, cfg.WriteTimeout)
 	var charset = "utf8mb4"
 	if cfg.Charset != "" {
 		charset = cfg.Charset
 	}
 	dns += "&charset=" + charset
+	if cfg.TLS != nil {
+		dns += "&tls=" + url.QueryEscape(cfg.tlsConfigName())
+	}
+	keys := make([]string, 0, len(cfg.Params))
+	for key := range cfg.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dns += "&" + key + "=" + url.QueryEscape(cfg.Params[key])
+	}
 	return dns
 }
 
@@ -0,0 +1,48 @@
+package dbmysql
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrStaleFencingToken fencing token 小于记录中已写入的 token，说明当前请求来自锁过期后的旧持有者
+var ErrStaleFencingToken = errors.New("stale fencing token")
+
+// UpdateWithFencingToken 基于 fencing token 的条件更新，只有记录中的 token 不大于当前 token 时才会更新，并写入当前 token，
+// token 列为 NULL 时视为0。db 需要通过 Model、Where 等指定要更新的记录，
+// 影响行数为0时从主库读取记录中的 token 区分原因：记录不存在时返回 gorm.ErrRecordNotFound，
+// token 大于当前 token 时返回 ErrStaleFencingToken，否则说明更新前后的值相同，视为更新成功
+func UpdateWithFencingToken(db *gorm.DB, column string, token int64, values map[string]any) error {
+	updates := make(map[string]any, len(values)+1)
+	for k, v := range values {
+		updates[k] = v
+	}
+	updates[column] = token
+
+	// 更新和读取共用调用方指定的条件，互不影响
+	base := db.Session(&gorm.Session{})
+	col := clause.Column{Name: column}
+	res := base.Where("COALESCE(?, 0) <= ?", col, token).Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	// 未开启 clientFoundRows 时 MySQL 只统计值发生变化的行，需要读取记录确认原因
+	var current int64
+	res = UsePrimary(base).Select("COALESCE(?, 0)", col).Limit(1).Scan(&current)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if current > token {
+		return ErrStaleFencingToken
+	}
+	return nil
+}
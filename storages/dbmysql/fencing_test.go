@@ -0,0 +1,74 @@
+package dbmysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestUpdateWithFencingToken(t *testing.T) {
+	db, openErr := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "root:123456@tcp(127.0.0.1:3306)/practice",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	assert.Nil(t, openErr)
+
+	type Order struct {
+		ID           uint64
+		Status       int
+		FencingToken int64
+	}
+	var sql string
+	registerErr := db.Callback().Update().After("gorm:update").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	assert.Nil(t, registerErr)
+
+	err := UpdateWithFencingToken(db.Model(&Order{}).Where("id = ?", 1), "fencing_token", 5, map[string]any{"status": 2})
+	// DryRun 模式不会真正执行，影响行数为0，之后读取 token 时不支持 DryRun
+	assert.ErrorIs(t, err, gorm.ErrDryRunModeUnsupported)
+	t.Log(sql)
+	assert.Contains(t, sql, "COALESCE(`fencing_token`, 0) <= ?")
+	assert.Contains(t, sql, "`fencing_token`=?")
+}
+
+type fencingTestOrder struct {
+	ID           uint64 `gorm:"primaryKey"`
+	Status       int
+	FencingToken *int64
+}
+
+func (fencingTestOrder) TableName() string {
+	return "dbmysql_fencing_test"
+}
+
+func TestUpdateWithFencingTokenResult(t *testing.T) {
+	db, err := InitMysql(&MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+	})
+	assert.Nil(t, err)
+	defer Close(db)
+	assert.Nil(t, db.AutoMigrate(&fencingTestOrder{}))
+	assert.Nil(t, db.Where("1 = 1").Delete(&fencingTestOrder{}).Error)
+	assert.Nil(t, db.Create(&fencingTestOrder{ID: 1}).Error)
+
+	update := func(id uint64, token int64, status int) error {
+		return UpdateWithFencingToken(db.Model(&fencingTestOrder{}).Where("id = ?", id), "fencing_token", token, map[string]any{"status": status})
+	}
+	// token 列为 NULL 时视为0
+	assert.Nil(t, update(1, 5, 1))
+	// 相同 token 写入相同的值不是过期
+	assert.Nil(t, update(1, 5, 1))
+	assert.ErrorIs(t, update(1, 4, 2), ErrStaleFencingToken)
+	assert.ErrorIs(t, update(2, 5, 1), gorm.ErrRecordNotFound)
+
+	var order fencingTestOrder
+	assert.Nil(t, db.Take(&order, 1).Error)
+	assert.Equal(t, 1, order.Status)
+	assert.Equal(t, int64(5), *order.FencingToken)
+}
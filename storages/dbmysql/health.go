@@ -0,0 +1,119 @@
+package dbmysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/morehao/golib/glog"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// instance InitMysql 创建的连接附带的资源，按主库的 *sql.DB 索引，同一连接派生的会话共享
+type instance struct {
+	resolver *dbresolver.DBResolver // 读写分离插件，未配置从库时为 nil
+	healthy  atomic.Bool
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+var instances sync.Map // *sql.DB -> *instance
+
+func getInstance(db *gorm.DB) (*sql.DB, *instance, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	if ins, ok := instances.Load(sqlDB); ok {
+		return sqlDB, ins.(*instance), nil
+	}
+	return sqlDB, nil, nil
+}
+
+// Ping 检查主库和所有从库是否可用
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, ins, err := getInstance(db)
+	if err != nil {
+		return err
+	}
+	if ins == nil || ins.resolver == nil {
+		return sqlDB.PingContext(ctx)
+	}
+	// 读写分离插件遍历的连接包含主库
+	return ins.resolver.Call(func(connPool gorm.ConnPool) error {
+		if pinger, ok := connPool.(interface{ PingContext(context.Context) error }); ok {
+			return pinger.PingContext(ctx)
+		}
+		return nil
+	})
+}
+
+// Healthy 返回最近一次健康检查的结果，未开启健康检查时总是返回 true
+func Healthy(db *gorm.DB) bool {
+	_, ins, err := getInstance(db)
+	if err != nil {
+		return false
+	}
+	return ins == nil || ins.healthy.Load()
+}
+
+// Stats 返回主库连接池的统计信息，可用于上报监控指标
+func Stats(db *gorm.DB) sql.DBStats {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
+}
+
+// Close 停止健康检查，关闭主库和所有从库的连接
+func Close(db *gorm.DB) error {
+	sqlDB, ins, err := getInstance(db)
+	if err != nil {
+		return err
+	}
+	if ins == nil {
+		return sqlDB.Close()
+	}
+	instances.Delete(sqlDB)
+	ins.stopOnce.Do(func() {
+		close(ins.stopChan)
+	})
+	if ins.resolver == nil {
+		return sqlDB.Close()
+	}
+	var errs []error
+	_ = ins.resolver.Call(func(connPool gorm.ConnPool) error {
+		if closer, ok := connPool.(interface{ Close() error }); ok {
+			errs = append(errs, closer.Close())
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// healthCheckLoop 定期检查连接，状态变化时记录日志
+func (cfg *MysqlConfig) healthCheckLoop(db *gorm.DB, ins *instance) {
+	ticker := time.NewTicker(cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ins.stopChan:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.HealthCheckInterval)
+			err := Ping(ctx, db)
+			cancel()
+			if err != nil && ins.healthy.Swap(false) {
+				glog.Errorf(context.Background(), "[dbmysql] health check of %s/%s failed: %v", cfg.Addr, cfg.Database, err)
+			} else if err == nil && !ins.healthy.Swap(true) {
+				glog.Infof(context.Background(), "[dbmysql] health check of %s/%s recovered", cfg.Addr, cfg.Database)
+			}
+		}
+	}
+}
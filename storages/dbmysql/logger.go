@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/morehao/golib/glog"
@@ -18,15 +19,46 @@ type ormLogger struct {
 	Database      string
 	MaxSqlLen     int
 	SlowThreshold time.Duration
+	LogLevel      logger.LogLevel
+	SampleRate    float64 // 执行成功且未超过慢SQL阈值的语句的日志采样率
 	Logger        glog.Logger
 }
 
 type ormConfig struct {
-	Service      string
-	Addr         string
-	Database     string
-	MaxSqlLen    int
-	loggerConfig *glog.LogConfig
+	Service       string
+	Addr          string
+	Database      string
+	MaxSqlLen     int
+	SlowThreshold time.Duration
+	LogLevel      logger.LogLevel
+	SampleRate    float64
+	loggerConfig  *glog.LogConfig
+}
+
+// LogLevel SQL 日志级别，与 gorm 的日志级别对应
+type LogLevel string
+
+const (
+	LogLevelSilent LogLevel = "silent" // 不记录日志
+	LogLevelError  LogLevel = "error"  // 只记录执行失败的语句
+	LogLevelWarn   LogLevel = "warn"   // 记录执行失败的语句和慢SQL
+	LogLevelInfo   LogLevel = "info"   // 记录所有语句
+)
+
+// gormLogLevel 转换为 gorm 的日志级别，为空时记录所有语句
+func (level LogLevel) gormLogLevel() (logger.LogLevel, error) {
+	switch level {
+	case LogLevelSilent:
+		return logger.Silent, nil
+	case LogLevelError:
+		return logger.Error, nil
+	case LogLevelWarn:
+		return logger.Warn, nil
+	case "", LogLevelInfo:
+		return logger.Info, nil
+	default:
+		return 0, fmt.Errorf("unsupported log level: %s", level)
+	}
 }
 
 func newOrmLogger(cfg *ormConfig) (*ormLogger, error) {
@@ -39,46 +71,72 @@ func newOrmLogger(cfg *ormConfig) (*ormLogger, error) {
 		return nil, err
 	}
 	return &ormLogger{
-		Service:   s,
-		Addr:      cfg.Addr,
-		Database:  cfg.Database,
-		MaxSqlLen: cfg.MaxSqlLen,
-		Logger:    l,
+		Service:       s,
+		Addr:          cfg.Addr,
+		Database:      cfg.Database,
+		MaxSqlLen:     cfg.MaxSqlLen,
+		SlowThreshold: cfg.SlowThreshold,
+		LogLevel:      cfg.LogLevel,
+		SampleRate:    cfg.SampleRate,
+		Logger:        l,
 	}, nil
 }
 
-// LogMode log mode
+// LogMode log mode，返回使用新级别的副本，db.Debug() 等只影响当前会话
 func (l *ormLogger) LogMode(level logger.LogLevel) logger.Interface {
-	return l
+	newLogger := *l
+	newLogger.LogLevel = level
+	return &newLogger
 }
 
 // Info print info
 func (l *ormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel < logger.Info {
+		return
+	}
 	formatMsg := fmt.Sprintf(msg, append([]interface{}{utils.FileWithLineNum()}, data...)...)
 	l.Logger.Infow(ctx, formatMsg, l.commonFields(ctx)...)
 }
 
 // Warn print warn messages
 func (l *ormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel < logger.Warn {
+		return
+	}
 	formatMsg := fmt.Sprintf(msg, append([]interface{}{utils.FileWithLineNum()}, data...)...)
 	l.Logger.Warnw(ctx, formatMsg, l.commonFields(ctx)...)
 }
 
 // Error print error messages
 func (l *ormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel < logger.Error {
+		return
+	}
 	formatMsg := fmt.Sprintf(msg, append([]interface{}{utils.FileWithLineNum()}, data...)...)
 	l.Logger.Errorw(ctx, formatMsg, l.commonFields(ctx)...)
 }
 
 // Trace print sql message
 func (l *ormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.LogLevel <= logger.Silent {
+		return
+	}
 	end := time.Now()
-	cost := glog.GetRequestCost(begin, end)
+	elapsed := end.Sub(begin)
+	// 过滤未找到数据的错误
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.SlowThreshold > 0 && elapsed >= l.SlowThreshold
+	switch {
+	case failed && l.LogLevel >= logger.Error:
+	case slow && l.LogLevel >= logger.Warn:
+	case !failed && !slow && l.LogLevel >= logger.Info && l.sampled():
+	default:
+		return
+	}
 
 	msg := "sql execute success"
 	var ralCode int
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		// 过滤未找到数据的错误
+	if failed {
 		msg = err.Error()
 		ralCode = -1
 	}
@@ -92,19 +150,29 @@ func (l *ormLogger) Trace(ctx context.Context, begin time.Time, fc func() (strin
 	fields = append(fields,
 		glog.KeyAffectedRows, rows,
 		// glog.KeyFile, fileLineNum,
-		glog.KeyCost, cost,
+		glog.KeyCost, glog.GetRequestCost(begin, end),
 		glog.KeyRalCode, ralCode,
 		glog.KeySql, sql,
 	)
 
-	if l.SlowThreshold > 0 && cost >= float64(l.SlowThreshold/time.Millisecond) {
-		msg = "slow sql"
-		l.Logger.Warnw(ctx, msg, fields...)
-	} else {
+	switch {
+	case failed:
+		l.Logger.Errorw(ctx, msg, fields...)
+	case slow:
+		l.Logger.Warnw(ctx, "slow sql", fields...)
+	default:
 		l.Logger.Debugw(ctx, msg, fields...)
 	}
 }
 
+// sampled 按采样率决定是否记录执行成功的语句，采样率不在 (0, 1) 范围内时全部记录
+func (l *ormLogger) sampled() bool {
+	if l.SampleRate <= 0 || l.SampleRate >= 1 {
+		return true
+	}
+	return rand.Float64() < l.SampleRate
+}
+
 func (l *ormLogger) commonFields(ctx context.Context) []interface{} {
 	fields := []interface{}{
 		glog.KeyAddr, l.Addr,
@@ -0,0 +1,80 @@
+package dbmysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morehao/golib/glog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordLogger 记录 SQL 日志的级别和内容
+type recordLogger struct {
+	glog.Logger
+	records []string
+}
+
+func (l *recordLogger) Debugw(ctx context.Context, msg string, kvs ...any) {
+	l.records = append(l.records, "debug:"+msg)
+}
+
+func (l *recordLogger) Warnw(ctx context.Context, msg string, kvs ...any) {
+	l.records = append(l.records, "warn:"+msg)
+}
+
+func (l *recordLogger) Errorw(ctx context.Context, msg string, kvs ...any) {
+	l.records = append(l.records, "error:"+msg)
+}
+
+func TestOrmLoggerTrace(t *testing.T) {
+	ctx := context.Background()
+	fc := func() (string, int64) { return "SELECT 1", 1 }
+	fast := time.Now()
+	slow := time.Now().Add(-time.Second)
+	failErr := errors.New("sql failed")
+
+	cases := []struct {
+		name  string
+		level logger.LogLevel
+		want  []string
+	}{
+		{name: "info", level: logger.Info, want: []string{"debug:sql execute success", "warn:slow sql", "error:sql failed", "debug:sql execute success"}},
+		{name: "warn", level: logger.Warn, want: []string{"warn:slow sql", "error:sql failed"}},
+		{name: "error", level: logger.Error, want: []string{"error:sql failed"}},
+		{name: "silent", level: logger.Silent, want: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			record := &recordLogger{}
+			l := &ormLogger{SlowThreshold: time.Millisecond * 500, LogLevel: c.level, Logger: record}
+			l.Trace(ctx, fast, fc, nil)
+			l.Trace(ctx, slow, fc, nil)
+			l.Trace(ctx, fast, fc, failErr)
+			l.Trace(ctx, fast, fc, gorm.ErrRecordNotFound)
+			assert.Equal(t, c.want, record.records)
+		})
+	}
+
+	// LogMode 返回新的副本，不影响原来的级别
+	record := &recordLogger{}
+	l := &ormLogger{LogLevel: logger.Silent, Logger: record}
+	l.LogMode(logger.Info).Trace(ctx, fast, fc, nil)
+	l.Trace(ctx, fast, fc, nil)
+	assert.Equal(t, []string{"debug:sql execute success"}, record.records)
+
+	// 采样只作用于执行成功的语句
+	record = &recordLogger{}
+	l = &ormLogger{LogLevel: logger.Info, SampleRate: 0.000001, Logger: record}
+	for i := 0; i < 100; i++ {
+		l.Trace(ctx, fast, fc, nil)
+	}
+	l.Trace(ctx, fast, fc, failErr)
+	assert.Equal(t, []string{"error:sql failed"}, record.records)
+
+	_, err := LogLevel("verbose").gormLogLevel()
+	assert.NotNil(t, err)
+}
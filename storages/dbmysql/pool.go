@@ -0,0 +1,116 @@
+package dbmysql
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 连接池和启动连接的默认配置
+const (
+	defaultMaxOpenConns    = 100
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = time.Hour
+	defaultConnMaxIdleTime = time.Minute * 10
+	defaultConnectRetry    = 3
+	defaultConnectBackoff  = time.Millisecond * 500
+	maxConnectBackoff      = time.Second * 10
+)
+
+// TLSConfig TLS 连接配置，主库和从库共用，ServerName 为空时使用各自地址中的主机名
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file"`              // CA 证书文件，为空时使用系统根证书
+	CertFile           string `yaml:"cert_file"`            // 客户端证书文件，需要双向认证时配置
+	KeyFile            string `yaml:"key_file"`             // 客户端私钥文件
+	ServerName         string `yaml:"server_name"`          // 校验的服务端证书名称
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // 跳过服务端证书校验，只用于测试环境
+}
+
+// applyDefaults 为未配置的连接池参数设置默认值
+func (cfg *MysqlConfig) applyDefaults() {
+	if cfg.MaxOpenConns == 0 {
+		cfg.MaxOpenConns = defaultMaxOpenConns
+	}
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = defaultMaxIdleConns
+	}
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = defaultConnMaxLifetime
+	}
+	if cfg.ConnMaxIdleTime == 0 {
+		cfg.ConnMaxIdleTime = defaultConnMaxIdleTime
+	}
+	if cfg.ConnectRetry == 0 {
+		cfg.ConnectRetry = defaultConnectRetry
+	}
+	if cfg.ConnectBackoff == 0 {
+		cfg.ConnectBackoff = defaultConnectBackoff
+	}
+}
+
+// configurePool 设置主库的连接池参数，参数为负数时表示不限制
+func (cfg *MysqlConfig) configurePool(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(max(cfg.MaxOpenConns, 0))
+	sqlDB.SetMaxIdleConns(cfg.maxIdleConns())
+	sqlDB.SetConnMaxLifetime(max(cfg.ConnMaxLifetime, 0))
+	sqlDB.SetConnMaxIdleTime(max(cfg.ConnMaxIdleTime, 0))
+}
+
+// configureResolverPool 设置从库的连接池参数，与主库相同
+func (cfg *MysqlConfig) configureResolverPool(resolver *dbresolver.DBResolver) {
+	resolver.SetMaxOpenConns(max(cfg.MaxOpenConns, 0)).
+		SetMaxIdleConns(cfg.maxIdleConns()).
+		SetConnMaxLifetime(max(cfg.ConnMaxLifetime, 0)).
+		SetConnMaxIdleTime(max(cfg.ConnMaxIdleTime, 0))
+}
+
+// maxIdleConns 返回最大空闲连接数，database/sql 中不大于0表示不保留空闲连接，
+// 因此不限制时使用 math.MaxInt，设置了最大打开连接数时 database/sql 会将其降为最大打开连接数
+func (cfg *MysqlConfig) maxIdleConns() int {
+	if cfg.MaxIdleConns < 0 {
+		return math.MaxInt
+	}
+	return cfg.MaxIdleConns
+}
+
+// tlsConfigName 注册到 mysql 驱动的 TLS 配置名称
+func (cfg *MysqlConfig) tlsConfigName() string {
+	return fmt.Sprintf("dbmysql-%s-%s", cfg.Addr, cfg.Database)
+}
+
+// registerTLS 加载证书并注册 TLS 配置，DSN 中通过 tls 参数引用
+func (cfg *MysqlConfig) registerTLS() error {
+	if cfg.TLS == nil {
+		return nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.TLS.ServerName,
+		InsecureSkipVerify: cfg.TLS.InsecureSkipVerify,
+	}
+	if cfg.TLS.CAFile != "" {
+		ca, err := os.ReadFile(cfg.TLS.CAFile)
+		if err != nil {
+			return fmt.Errorf("read ca file failed: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificate found in ca file %s", cfg.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load client certificate failed: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return mysql.RegisterTLSConfig(cfg.tlsConfigName(), tlsConfig)
+}
@@ -0,0 +1,100 @@
+package dbmysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDns(t *testing.T) {
+	cfg := &MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+		TLS:      &TLSConfig{InsecureSkipVerify: true},
+		Params:   map[string]string{"interpolateParams": "true", "collation": "utf8mb4_general_ci"},
+	}
+	assert.Nil(t, cfg.registerTLS())
+	dns := cfg.buildDns()
+	t.Log(dns)
+	assert.Contains(t, dns, "&tls=dbmysql-127.0.0.1%3A3306-practice")
+	assert.Contains(t, dns, "&collation=utf8mb4_general_ci&interpolateParams=true")
+
+	cfg.TLS = &TLSConfig{CAFile: "not_exist.pem"}
+	assert.NotNil(t, cfg.registerTLS())
+}
+
+func TestPoolAndHealthCheck(t *testing.T) {
+	cfg := &MysqlConfig{
+		Addr:                "127.0.0.1:3306",
+		Database:            "practice",
+		User:                "root",
+		Password:            "123456",
+		MaxOpenConns:        8,
+		HealthCheckInterval: time.Millisecond * 20,
+		Replicas:            []ReplicaConfig{{Addr: "127.0.0.1:3306"}},
+	}
+	db, err := InitMysql(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, Stats(db).MaxOpenConnections)
+
+	ctx := context.Background()
+	assert.Nil(t, Ping(ctx, db))
+	time.Sleep(time.Millisecond * 50)
+	assert.True(t, Healthy(db.WithContext(ctx)))
+
+	assert.Nil(t, Close(db))
+	assert.NotNil(t, Ping(ctx, db))
+}
+
+func TestUnlimitedIdleConns(t *testing.T) {
+	cfg := &MysqlConfig{
+		Addr:         "127.0.0.1:3306",
+		Database:     "practice",
+		User:         "root",
+		Password:     "123456",
+		MaxOpenConns: 4,
+		MaxIdleConns: -1,
+	}
+	db, err := InitMysql(cfg)
+	assert.Nil(t, err)
+	defer Close(db)
+
+	// 同时占用所有连接，归还后全部保留为空闲连接
+	ctx := context.Background()
+	sqlDB, err := db.DB()
+	assert.Nil(t, err)
+	conns := make([]*sql.Conn, 0, cfg.MaxOpenConns)
+	for i := 0; i < cfg.MaxOpenConns; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		assert.Nil(t, err)
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		assert.Nil(t, conn.Close())
+	}
+	stats := Stats(db)
+	assert.Equal(t, cfg.MaxOpenConns, stats.Idle)
+	assert.Equal(t, int64(0), stats.MaxIdleClosed)
+}
+
+func TestConnectRetry(t *testing.T) {
+	cfg := &MysqlConfig{
+		Addr:           "127.0.0.1:1",
+		Database:       "practice",
+		User:           "root",
+		Password:       "123456",
+		Timeout:        time.Millisecond * 100,
+		ConnectRetry:   2,
+		ConnectBackoff: time.Millisecond * 20,
+	}
+	start := time.Now()
+	_, err := InitMysql(cfg)
+	assert.NotNil(t, err)
+	// 两次重试分别等待20毫秒和40毫秒
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*60)
+}
@@ -0,0 +1,119 @@
+package dbmysql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 按名称注册的数据库连接
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*gorm.DB)
+)
+
+// InitMysqls 并行初始化多个数据库并按名称注册，之后通过 Get 获取，
+// cfgs 的 key 为数据库名称，可以在配置结构体中定义 map[string]*MysqlConfig 字段后通过 conf.LoadConfig 从 YAML 文件加载，
+// 任一数据库初始化失败或名称已注册时，关闭本次已初始化的连接并返回错误
+func InitMysqls(cfgs map[string]*MysqlConfig, opts ...Option) error {
+	names := make([]string, 0, len(cfgs))
+	for name := range cfgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if cfgs[name] == nil {
+			return fmt.Errorf("mysql config %q is nil", name)
+		}
+	}
+
+	registryMu.RLock()
+	for _, name := range names {
+		if _, ok := registry[name]; ok {
+			registryMu.RUnlock()
+			return fmt.Errorf("mysql %s is already registered", name)
+		}
+	}
+	registryMu.RUnlock()
+
+	dbs := make([]*gorm.DB, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := InitMysql(cfgs[name], opts...)
+			if err != nil {
+				errs[i] = fmt.Errorf("init mysql %s failed: %w", name, err)
+				return
+			}
+			dbs[i] = db
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		closeAll(dbs)
+		return err
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, name := range names {
+		if _, ok := registry[name]; ok {
+			closeAll(dbs)
+			return fmt.Errorf("mysql %s is already registered", name)
+		}
+	}
+	for i, name := range names {
+		registry[name] = dbs[i]
+	}
+	return nil
+}
+
+// Register 按名称注册数据库连接，名称已注册时返回错误
+func Register(name string, db *gorm.DB) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("mysql %s is already registered", name)
+	}
+	registry[name] = db
+	return nil
+}
+
+// Get 获取已注册的数据库连接，未注册时返回 nil
+func Get(name string) *gorm.DB {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+// CloseAll 关闭并注销所有已注册的数据库连接，用于服务退出时释放连接
+func CloseAll() error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	var errs []error
+	for name, db := range registry {
+		if err := Close(db); err != nil {
+			errs = append(errs, fmt.Errorf("close mysql %s failed: %w", name, err))
+		}
+	}
+	registry = make(map[string]*gorm.DB)
+	return errors.Join(errs...)
+}
+
+// closeAll 关闭初始化成功的连接，忽略关闭时的错误
+func closeAll(dbs []*gorm.DB) {
+	for _, db := range dbs {
+		if db != nil {
+			_ = Close(db)
+		}
+	}
+}
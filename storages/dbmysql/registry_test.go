@@ -0,0 +1,65 @@
+package dbmysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRegistry(t *testing.T) {
+	var cfg struct {
+		Mysql map[string]*MysqlConfig `yaml:"mysql"`
+	}
+	content := `
+mysql:
+  orders:
+    addr: 127.0.0.1:3306
+    database: practice
+    user: root
+    password: "123456"
+  users:
+    addr: 127.0.0.1:3306
+    database: practice
+    user: root
+    password: "123456"
+    max_open_conns: 5
+`
+	assert.Nil(t, yaml.Unmarshal([]byte(content), &cfg))
+	assert.Nil(t, InitMysqls(cfg.Mysql))
+	defer CloseAll()
+	// 默认值不会写回共享的配置
+	assert.Equal(t, 0, cfg.Mysql["orders"].MaxOpenConns)
+
+	orders := Get("orders")
+	assert.NotNil(t, orders)
+	var result int
+	assert.Nil(t, orders.Raw("SELECT 1 FROM dual").Scan(&result).Error)
+	assert.Equal(t, 5, Stats(Get("users")).MaxOpenConnections)
+	assert.Nil(t, Get("unknown"))
+
+	// 名称已注册
+	assert.NotNil(t, InitMysqls(map[string]*MysqlConfig{"orders": cfg.Mysql["orders"]}))
+	assert.NotNil(t, Register("orders", orders))
+
+	// 任一数据库初始化失败时全部失败
+	err := InitMysqls(map[string]*MysqlConfig{
+		"logs": {Addr: "127.0.0.1:3306", Database: "practice", User: "root", Password: "123456"},
+		"bad":  {Addr: "127.0.0.1:1", Database: "practice", User: "root", Timeout: time.Millisecond * 100, ConnectRetry: -1},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad")
+	assert.Nil(t, Get("logs"))
+
+	// 配置为空时在初始化前返回错误
+	err = InitMysqls(map[string]*MysqlConfig{
+		"logs":  {Addr: "127.0.0.1:3306", Database: "practice", User: "root", Password: "123456"},
+		"empty": nil,
+	})
+	assert.EqualError(t, err, `mysql config "empty" is nil`)
+	assert.Nil(t, Get("logs"))
+
+	assert.Nil(t, CloseAll())
+	assert.Nil(t, Get("orders"))
+}
@@ -0,0 +1,132 @@
+package dbmysql
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReplicaConfig 从库配置，用户名、密码和数据库名与主库相同
+type ReplicaConfig struct {
+	Addr   string `yaml:"addr"`   // 地址
+	Weight int    `yaml:"weight"` // 权重，只在 weighted 策略下使用，默认为1
+}
+
+// ReplicaPolicy 从库的负载均衡策略
+type ReplicaPolicy string
+
+const (
+	ReplicaPolicyRandom     ReplicaPolicy = "random"      // 随机选择从库
+	ReplicaPolicyRoundRobin ReplicaPolicy = "round_robin" // 轮询从库
+	ReplicaPolicyWeighted   ReplicaPolicy = "weighted"    // 按权重随机选择从库
+)
+
+type primaryCtxKey struct{}
+
+// WithPrimary 返回强制使用主库的 ctx，用于写入后立即读取等需要读到最新数据的场景
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// IsPrimary 判断 ctx 是否强制使用主库
+func IsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return primary
+}
+
+// UsePrimary 当前语句强制使用主库
+func UsePrimary(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Write)
+}
+
+// useReplicas 注册读写分离插件，查询和 SELECT 原生语句路由到从库，
+// 写入、SELECT ... FOR UPDATE 和事务内的语句使用主库，未配置从库时返回 nil
+func (cfg *MysqlConfig) useReplicas(db *gorm.DB, policy dbresolver.Policy) (*dbresolver.DBResolver, error) {
+	if len(cfg.Replicas) == 0 {
+		return nil, nil
+	}
+	replicas := make([]gorm.Dialector, 0, len(cfg.Replicas))
+	for _, replica := range cfg.Replicas {
+		replicas = append(replicas, mysql.Open(cfg.buildDnsWithAddr(replica.Addr)))
+	}
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   policy,
+	})
+	if err := db.Use(resolver); err != nil {
+		return nil, err
+	}
+	cfg.configureResolverPool(resolver)
+	if err := registerPrimaryCallbacks(db); err != nil {
+		return nil, err
+	}
+	return resolver, nil
+}
+
+// replicaPolicy 创建从库的负载均衡策略
+func (cfg *MysqlConfig) replicaPolicy() (dbresolver.Policy, error) {
+	switch cfg.ReplicaPolicy {
+	case "", ReplicaPolicyRandom:
+		return dbresolver.RandomPolicy{}, nil
+	case ReplicaPolicyRoundRobin:
+		return dbresolver.StrictRoundRobinPolicy(), nil
+	case ReplicaPolicyWeighted:
+		policy := &weightedPolicy{weights: make([]int, 0, len(cfg.Replicas))}
+		for _, replica := range cfg.Replicas {
+			if replica.Weight < 0 {
+				return nil, fmt.Errorf("weight of replica %s must not be negative", replica.Addr)
+			}
+			weight := replica.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			policy.weights = append(policy.weights, weight)
+			policy.total += weight
+		}
+		return policy, nil
+	default:
+		return nil, fmt.Errorf("unsupported replica policy: %s", cfg.ReplicaPolicy)
+	}
+}
+
+// registerPrimaryCallbacks 检查 ctx，WithPrimary 的 ctx 中的查询使用主库，
+// dbresolver.Write.ModifyStatement 会重新执行读写分离，因此与 gorm:db_resolver 的执行顺序无关
+func registerPrimaryCallbacks(db *gorm.DB) error {
+	usePrimary := func(db *gorm.DB) {
+		if db.Statement.Context != nil && IsPrimary(db.Statement.Context) {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("*").Register("dbmysql:use_primary", usePrimary); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("*").Register("dbmysql:use_primary", usePrimary); err != nil {
+		return err
+	}
+	return callbacks.Raw().Before("*").Register("dbmysql:use_primary", usePrimary)
+}
+
+// weightedPolicy 按权重随机选择从库，weights 与从库的配置顺序一致
+type weightedPolicy struct {
+	weights []int
+	total   int
+}
+
+func (p *weightedPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	if len(connPools) != len(p.weights) {
+		return connPools[rand.Intn(len(connPools))]
+	}
+	n := rand.Intn(p.total)
+	for i, weight := range p.weights {
+		if n < weight {
+			return connPools[i]
+		}
+		n -= weight
+	}
+	return connPools[len(connPools)-1]
+}
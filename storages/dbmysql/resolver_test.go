@@ -0,0 +1,71 @@
+package dbmysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReplicas(t *testing.T) {
+	cfg := &MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+		Replicas: []ReplicaConfig{
+			{Addr: "127.0.0.1:3306", Weight: 1},
+			{Addr: "127.0.0.1:3306", Weight: 3},
+		},
+		ReplicaPolicy: ReplicaPolicyWeighted,
+	}
+	db, err := InitMysql(cfg)
+	assert.Nil(t, err)
+	primary, err := db.DB()
+	assert.Nil(t, err)
+
+	// 记录每条语句实际使用的连接池
+	var connPool gorm.ConnPool
+	capture := func(tx *gorm.DB) {
+		connPool = tx.Statement.ConnPool
+	}
+	assert.Nil(t, db.Callback().Query().After("gorm:db_resolver").Register("test:capture_conn_pool", capture))
+	assert.Nil(t, db.Callback().Row().After("gorm:db_resolver").Register("test:capture_conn_pool", capture))
+	assert.Nil(t, db.Callback().Raw().After("gorm:db_resolver").Register("test:capture_conn_pool", capture))
+	ctx := context.Background()
+
+	var result int
+	assert.Nil(t, db.WithContext(ctx).Raw("SELECT 1 FROM dual").Scan(&result).Error)
+	assert.NotNil(t, connPool)
+	assert.NotSame(t, primary, connPool)
+
+	// 强制使用主库
+	assert.Nil(t, db.WithContext(WithPrimary(ctx)).Raw("SELECT 1 FROM dual").Scan(&result).Error)
+	assert.Same(t, primary, connPool)
+	assert.Nil(t, UsePrimary(db.WithContext(ctx)).Raw("SELECT 1 FROM dual").Scan(&result).Error)
+	assert.Same(t, primary, connPool)
+
+	// 非 SELECT 语句使用主库
+	assert.Nil(t, db.WithContext(ctx).Exec("SET @dbmysql_test = 1").Error)
+	assert.Same(t, primary, connPool)
+
+	// 事务内的查询使用主库的事务连接
+	assert.Nil(t, db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Raw("SELECT 1 FROM dual").Scan(&result).Error
+	}))
+	_, isTx := connPool.(gorm.TxCommitter)
+	assert.True(t, isTx)
+
+	cfg.ReplicaPolicy = "unknown"
+	_, err = InitMysql(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestWeightedPolicy(t *testing.T) {
+	policy := &weightedPolicy{weights: []int{1, 0}, total: 1}
+	pools := []gorm.ConnPool{&gorm.PreparedStmtDB{}, &gorm.PreparedStmtDB{}}
+	for i := 0; i < 10; i++ {
+		assert.Same(t, pools[0], policy.Resolve(pools))
+	}
+}
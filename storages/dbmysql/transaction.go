@@ -0,0 +1,128 @@
+package dbmysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/morehao/golib/glog"
+	"gorm.io/gorm"
+)
+
+// 可重试的 MySQL 错误码
+const (
+	errLockWaitTimeout uint16 = 1205 // 锁等待超时
+	errDeadlock        uint16 = 1213 // 死锁
+)
+
+// txCtxKey 按主库连接区分 ctx 中的事务，同一个 ctx 中可以同时存在不同数据库的事务
+type txCtxKey struct {
+	pool *sql.DB
+}
+
+// TxOption 是一个函数类型，用于设置事务的选项
+type TxOption func(cfg *txConfig)
+
+type txConfig struct {
+	maxRetries int
+	backoff    time.Duration
+	txOptions  *sql.TxOptions
+}
+
+// WithMaxRetries 设置死锁和锁等待超时时的最大重试次数，默认为3，为0时不重试
+func WithMaxRetries(maxRetries int) TxOption {
+	return func(cfg *txConfig) {
+		cfg.maxRetries = maxRetries
+	}
+}
+
+// WithRetryBackoff 设置第一次重试前的等待时间，之后每次翻倍并加入随机抖动，默认为50毫秒，不能为负数
+func WithRetryBackoff(backoff time.Duration) TxOption {
+	return func(cfg *txConfig) {
+		cfg.backoff = backoff
+	}
+}
+
+// WithTxOptions 设置事务的隔离级别和只读属性
+func WithTxOptions(txOptions *sql.TxOptions) TxOption {
+	return func(cfg *txConfig) {
+		cfg.txOptions = txOptions
+	}
+}
+
+// Transaction 在事务中执行 fn，事务保存在传给 fn 的 ctx 中，DAO 通过 FromContext 获取，
+// fn 返回错误或 panic 时回滚，否则提交；ctx 中已有同一数据库的事务时使用 savepoint 嵌套执行，
+// 嵌套事务出错只回滚到 savepoint，由外层决定是否继续；
+// 最外层事务遇到死锁或锁等待超时时整体重试，fn 可能被执行多次，不应包含数据库之外的副作用，
+// 等待重试期间 ctx 结束时返回 ctx.Err()
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts ...TxOption) error {
+	pool, err := db.DB()
+	if err != nil {
+		return err
+	}
+	key := txCtxKey{pool: pool}
+	run := func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, key, tx))
+	}
+	if tx, ok := ctx.Value(key).(*gorm.DB); ok {
+		// 死锁时 MySQL 会回滚整个事务，嵌套事务不重试，由最外层重试
+		return tx.WithContext(ctx).Transaction(run)
+	}
+
+	cfg := &txConfig{
+		maxRetries: 3,                     // 默认最多重试3次
+		backoff:    time.Millisecond * 50, // 默认第一次重试前等待50毫秒
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.backoff < 0 {
+		return errors.New("retry backoff must not be negative")
+	}
+	backoff := cfg.backoff
+	for attempt := 0; ; attempt++ {
+		var txOptions []*sql.TxOptions
+		if cfg.txOptions != nil {
+			txOptions = append(txOptions, cfg.txOptions)
+		}
+		err = db.WithContext(ctx).Transaction(run, txOptions...)
+		if err == nil || !IsRetryableTxError(err) || attempt >= cfg.maxRetries {
+			return err
+		}
+
+		// 等待时间在 [backoff/2, backoff) 之间随机，避免冲突的事务同时重试
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		glog.Warnf(ctx, "[dbmysql] transaction failed, retry after %s: %v", wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// 上一次的错误已记录日志，返回 ctx 的错误以便调用方区分取消和重试耗尽
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// FromContext 返回 ctx 中 db 对应的事务，没有事务时返回使用 ctx 的 db
+func FromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if pool, err := db.DB(); err == nil {
+		if tx, ok := ctx.Value(txCtxKey{pool: pool}).(*gorm.DB); ok {
+			return tx.WithContext(ctx)
+		}
+	}
+	return db.WithContext(ctx)
+}
+
+// IsRetryableTxError 判断错误是否为死锁或锁等待超时，此时重试整个事务通常可以成功
+func IsRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == errDeadlock || mysqlErr.Number == errLockWaitTimeout
+}
@@ -0,0 +1,150 @@
+package dbmysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type txTestRecord struct {
+	ID   uint64 `gorm:"primaryKey"`
+	Name string `gorm:"size:64"`
+}
+
+func (txTestRecord) TableName() string {
+	return "dbmysql_tx_test"
+}
+
+func TestTransaction(t *testing.T) {
+	db, err := InitMysql(&MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+	})
+	assert.Nil(t, err)
+	defer Close(db)
+	assert.Nil(t, db.AutoMigrate(&txTestRecord{}))
+	assert.Nil(t, db.Where("1 = 1").Delete(&txTestRecord{}).Error)
+	ctx := context.Background()
+
+	count := func() int64 {
+		var n int64
+		assert.Nil(t, db.Model(&txTestRecord{}).Count(&n).Error)
+		return n
+	}
+
+	// fn 返回错误时回滚，FromContext 获取 ctx 中的事务
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		tx := FromContext(ctx, db)
+		_, isTx := tx.Statement.ConnPool.(gorm.TxCommitter)
+		assert.True(t, isTx)
+		assert.Nil(t, tx.Create(&txTestRecord{Name: "rollback"}).Error)
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, int64(0), count())
+
+	// 没有事务时 FromContext 返回原来的连接
+	_, isTx := FromContext(ctx, db).Statement.ConnPool.(gorm.TxCommitter)
+	assert.False(t, isTx)
+}
+
+func TestNestedTransaction(t *testing.T) {
+	db, err := InitMysql(&MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+	})
+	assert.Nil(t, err)
+	defer Close(db)
+	assert.Nil(t, db.AutoMigrate(&txTestRecord{}))
+	assert.Nil(t, db.Where("1 = 1").Delete(&txTestRecord{}).Error)
+	ctx := context.Background()
+
+	// 嵌套事务出错只回滚到 savepoint
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		if err := FromContext(ctx, db).Create(&txTestRecord{Name: "outer"}).Error; err != nil {
+			return err
+		}
+		nestedErr := Transaction(ctx, db, func(ctx context.Context) error {
+			assert.Nil(t, FromContext(ctx, db).Create(&txTestRecord{Name: "inner"}).Error)
+			return assert.AnError
+		})
+		assert.ErrorIs(t, nestedErr, assert.AnError)
+		return nil
+	})
+	assert.Nil(t, err)
+	var names []string
+	assert.Nil(t, db.Model(&txTestRecord{}).Pluck("name", &names).Error)
+	assert.Equal(t, []string{"outer"}, names)
+}
+
+func TestTransactionRetry(t *testing.T) {
+	db, err := InitMysql(&MysqlConfig{
+		Addr:     "127.0.0.1:3306",
+		Database: "practice",
+		User:     "root",
+		Password: "123456",
+	})
+	assert.Nil(t, err)
+	defer Close(db)
+	ctx := context.Background()
+
+	// 死锁和锁等待超时时重试
+	attempts := 0
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return &mysql.MySQLError{Number: errDeadlock, Message: "Deadlock found when trying to get lock"}
+		}
+		return nil
+	}, WithRetryBackoff(time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// 超过最大重试次数后返回错误
+	attempts = 0
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		attempts++
+		return &mysql.MySQLError{Number: errLockWaitTimeout, Message: "Lock wait timeout exceeded"}
+	}, WithMaxRetries(1), WithRetryBackoff(time.Millisecond))
+	assert.True(t, IsRetryableTxError(err))
+	assert.Equal(t, 2, attempts)
+
+	// 其他错误不重试
+	attempts = 0
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		attempts++
+		return errors.New("business error")
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// 等待重试期间 ctx 结束时返回 ctx 的错误
+	attempts = 0
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel()
+	err = Transaction(timeoutCtx, db, func(ctx context.Context) error {
+		attempts++
+		return &mysql.MySQLError{Number: errDeadlock, Message: "Deadlock found when trying to get lock"}
+	}, WithRetryBackoff(time.Second))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, IsRetryableTxError(err))
+	assert.Equal(t, 1, attempts)
+
+	// 等待时间为负数时直接返回错误
+	attempts = 0
+	err = Transaction(ctx, db, func(ctx context.Context) error {
+		attempts++
+		return nil
+	}, WithRetryBackoff(-time.Second))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, attempts)
+}